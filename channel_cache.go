@@ -0,0 +1,304 @@
+package pusher
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+ChannelCache is a `ClientInterface` which caches the responses of `Channel`,
+`Channels` and `GetChannelUsers` for a TTL per endpoint. Identical calls made
+while a request is in flight wait for its response instead of making their own.
+
+Everything cached about a channel is invalidated when an event is triggered on
+it through the cache, or when a webhook about it is received by `Webhook` or
+passed to `InvalidateWebhook`. A TTL of zero disables caching of that endpoint,
+though identical concurrent calls are still collapsed into one request.
+
+	cache := &pusher.ChannelCache{
+		ClientInterface: client,
+		ChannelTTL:      time.Second,
+		ChannelsTTL:     5 * time.Second,
+	}
+	channel, err := cache.Channel("presence-room", pusher.ChannelParams{})
+*/
+type ChannelCache struct {
+	ClientInterface
+
+	ChannelTTL  time.Duration
+	ChannelsTTL time.Duration
+	UsersTTL    time.Duration
+
+	mutex   sync.Mutex
+	entries map[string]*channelCacheEntry
+	calls   map[string]*channelCacheCall
+	stats   CacheStats
+	now     func() time.Time
+}
+
+var _ ClientInterface = (*ChannelCache)(nil)
+
+// eventCodec returns the Codec of the wrapped client.
+func (c *ChannelCache) eventCodec() Codec {
+	return clientCodec(c.ClientInterface)
+}
+
+// CacheStats counts how the calls to a ChannelCache were answered.
+type CacheStats struct {
+	Hits      uint64 // answered from the cache
+	Misses    uint64 // answered by a request to Pusher
+	Coalesced uint64 // answered by an identical request already in flight
+}
+
+type channelCacheEntry struct {
+	value   interface{}
+	expires time.Time
+	channel string // the channel described, or
+	prefix  string // the prefix of the channels listed, when list is set
+	list    bool
+}
+
+// about reports whether the entry may describe channel.
+func (e *channelCacheEntry) about(channel string) bool {
+	if e.list {
+		return strings.HasPrefix(channel, e.prefix)
+	}
+	return e.channel == channel
+}
+
+type channelCacheCall struct {
+	entry       channelCacheEntry
+	done        chan struct{}
+	value       interface{}
+	err         error
+	invalidated bool
+}
+
+func (c *ChannelCache) currentTime() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+	return c.now()
+}
+
+func (c *ChannelCache) get(key string, entry channelCacheEntry, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	c.mutex.Lock()
+	if c.entries == nil {
+		c.entries = make(map[string]*channelCacheEntry)
+		c.calls = make(map[string]*channelCacheCall)
+	}
+	if cached, ok := c.entries[key]; ok {
+		if c.currentTime().Before(cached.expires) {
+			c.stats.Hits++
+			c.mutex.Unlock()
+			return cached.value, nil
+		}
+		delete(c.entries, key)
+	}
+	if call, ok := c.calls[key]; ok {
+		c.stats.Coalesced++
+		c.mutex.Unlock()
+		<-call.done
+		return call.value, call.err
+	}
+	c.stats.Misses++
+	call := &channelCacheCall{entry: entry, done: make(chan struct{})}
+	c.calls[key] = call
+	c.mutex.Unlock()
+
+	// The call is completed in a defer, so that a panicking fetch does not
+	// block the coalesced callers forever.
+	fetched := false
+	defer func() {
+		c.mutex.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+		if fetched && call.err == nil && ttl > 0 && !call.invalidated {
+			entry.value = call.value
+			entry.expires = c.currentTime().Add(ttl)
+			c.entries[key] = &entry
+		}
+		c.mutex.Unlock()
+		if !fetched {
+			call.err = errChannelCacheFetchPanicked
+		}
+		close(call.done)
+	}()
+	call.value, call.err = fetch()
+	fetched = true
+	return call.value, call.err
+}
+
+// errChannelCacheFetchPanicked is returned to the callers coalesced with a
+// request which panicked.
+var errChannelCacheFetchPanicked = errors.New("The coalesced request to the channel state panicked")
+
+// Channel implements ClientInterface, caching the state for ChannelTTL.
+func (c *ChannelCache) Channel(name string, params ChannelParams) (*Channel, error) {
+	key := "channel\x00" + name + "\x00" + joinInfo(params.Info, params.InfoAttributes)
+	value, err := c.get(key, channelCacheEntry{channel: name}, c.ChannelTTL, func() (interface{}, error) {
+		return c.ClientInterface.Channel(name, params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	channel := *value.(*Channel)
+	return &channel, nil
+}
+
+// Channels implements ClientInterface, caching the list for ChannelsTTL.
+func (c *ChannelCache) Channels(params ChannelsParams) (*ChannelsList, error) {
+	prefix := ""
+	if params.FilterByPrefix != nil {
+		prefix = *params.FilterByPrefix
+	}
+	key := "channels\x00" + prefix + "\x00" + joinInfo(params.Info, params.InfoAttributes)
+	value, err := c.get(key, channelCacheEntry{prefix: prefix, list: true}, c.ChannelsTTL, func() (interface{}, error) {
+		return c.ClientInterface.Channels(params)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cached := value.(*ChannelsList)
+	channels := &ChannelsList{Channels: make(map[string]ChannelListItem, len(cached.Channels))}
+	for name, item := range cached.Channels {
+		channels.Channels[name] = item
+	}
+	return channels, nil
+}
+
+// GetChannelUsers implements ClientInterface, caching the users for UsersTTL.
+func (c *ChannelCache) GetChannelUsers(name string) (*Users, error) {
+	value, err := c.get("users\x00"+name, channelCacheEntry{channel: name}, c.UsersTTL, func() (interface{}, error) {
+		return c.ClientInterface.GetChannelUsers(name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	cached := value.(*Users)
+	return &Users{List: append([]User{}, cached.List...)}, nil
+}
+
+// Trigger implements ClientInterface, invalidating the channel.
+func (c *ChannelCache) Trigger(channel string, eventName string, data interface{}) error {
+	defer c.Invalidate(channel)
+	return c.ClientInterface.Trigger(channel, eventName, data)
+}
+
+// TriggerWithParams implements ClientInterface, invalidating the channel.
+func (c *ChannelCache) TriggerWithParams(channel string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	defer c.Invalidate(channel)
+	return c.ClientInterface.TriggerWithParams(channel, eventName, data, params)
+}
+
+// TriggerMulti implements ClientInterface, invalidating the channels.
+func (c *ChannelCache) TriggerMulti(channels []string, eventName string, data interface{}) error {
+	defer c.Invalidate(channels...)
+	return c.ClientInterface.TriggerMulti(channels, eventName, data)
+}
+
+// TriggerMultiWithParams implements ClientInterface, invalidating the channels.
+func (c *ChannelCache) TriggerMultiWithParams(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	defer c.Invalidate(channels...)
+	return c.ClientInterface.TriggerMultiWithParams(channels, eventName, data, params)
+}
+
+// TriggerBatch implements ClientInterface, invalidating the channels.
+func (c *ChannelCache) TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error) {
+	channels := make([]string, len(batch))
+	for i, event := range batch {
+		channels[i] = event.Channel
+	}
+	defer c.Invalidate(channels...)
+	return c.ClientInterface.TriggerBatch(batch)
+}
+
+// Webhook implements ClientInterface, invalidating the channels of a valid
+// webhook.
+func (c *ChannelCache) Webhook(header http.Header, body []byte) (*Webhook, error) {
+	webhook, err := c.ClientInterface.Webhook(header, body)
+	if err != nil {
+		return nil, err
+	}
+	c.InvalidateWebhook(webhook)
+	return webhook, nil
+}
+
+/*
+InvalidateWebhook invalidates the channels of a webhook verified elsewhere, such
+as by a `WebhookVerifier`.
+*/
+func (c *ChannelCache) InvalidateWebhook(webhook *Webhook) {
+	channels := make([]string, len(webhook.Events))
+	for i, event := range webhook.Events {
+		channels[i] = event.Channel
+	}
+	c.Invalidate(channels...)
+}
+
+/*
+Invalidate discards everything cached about the channels, including the lists
+which may contain them. Responses to requests in flight are not cached.
+*/
+func (c *ChannelCache) Invalidate(channels ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for _, channel := range channels {
+		for key, entry := range c.entries {
+			if entry.about(channel) {
+				delete(c.entries, key)
+			}
+		}
+		for key, call := range c.calls {
+			if call.entry.about(channel) {
+				call.invalidated = true
+				delete(c.calls, key)
+			}
+		}
+	}
+}
+
+// Purge discards everything cached.
+func (c *ChannelCache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for key, call := range c.calls {
+		call.invalidated = true
+		delete(c.calls, key)
+	}
+	c.entries = nil
+}
+
+/*
+Occupancy reports whether a channel is occupied, and whether that is known from
+an unexpired response to `Channel` or `Channels`. It makes no requests.
+*/
+func (c *ChannelCache) Occupancy(channel string) (occupied, known bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	now := c.currentTime()
+	for _, entry := range c.entries {
+		if !now.Before(entry.expires) || !entry.about(channel) {
+			continue
+		}
+		switch value := entry.value.(type) {
+		case *Channel:
+			return value.Occupied, true
+		case *ChannelsList:
+			_, occupied := value.Channels[channel]
+			return occupied, true
+		}
+	}
+	return false, false
+}
+
+// Stats returns how the calls to the cache have been answered so far.
+func (c *ChannelCache) Stats() CacheStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
@@ -0,0 +1,217 @@
+package pusher
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type countingClient struct {
+	*Client
+
+	mutex   sync.Mutex
+	calls   int
+	release chan struct{}
+	err     error
+	panics  bool
+}
+
+func (c *countingClient) count() {
+	c.mutex.Lock()
+	c.calls++
+	c.mutex.Unlock()
+	if c.release != nil {
+		<-c.release
+	}
+}
+
+func (c *countingClient) Channel(name string, params ChannelParams) (*Channel, error) {
+	c.count()
+	if c.panics {
+		panic("upstream failure")
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &Channel{Name: name, Occupied: true}, nil
+}
+
+func (c *countingClient) Channels(params ChannelsParams) (*ChannelsList, error) {
+	c.count()
+	return &ChannelsList{Channels: map[string]ChannelListItem{"presence-room": {UserCount: 1}}}, nil
+}
+
+func (c *countingClient) Trigger(channel string, eventName string, data interface{}) error {
+	return nil
+}
+
+func (c *countingClient) Calls() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.calls
+}
+
+func TestChannelCacheHitsUntilExpiry(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}}
+	now := time.Now()
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Second, now: func() time.Time { return now }}
+
+	for i := 0; i < 3; i++ {
+		channel, err := cache.Channel("my-channel", ChannelParams{})
+		assert.NoError(t, err)
+		assert.Equal(t, "my-channel", channel.Name)
+	}
+	assert.Equal(t, 1, upstream.Calls())
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 1}, cache.Stats())
+
+	attributes := "subscription_count"
+	_, err := cache.Channel("my-channel", ChannelParams{Info: &attributes})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, upstream.Calls())
+
+	now = now.Add(time.Second)
+	_, err = cache.Channel("my-channel", ChannelParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, upstream.Calls())
+}
+
+func TestChannelCacheReturnsCopies(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelsTTL: time.Minute}
+
+	channels, err := cache.Channels(ChannelsParams{})
+	assert.NoError(t, err)
+	delete(channels.Channels, "presence-room")
+
+	channels, err = cache.Channels(ChannelsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, channels.Channels, 1)
+}
+
+func TestChannelCacheDoesNotCacheErrors(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}, err: errors.New("unavailable")}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute}
+
+	_, err := cache.Channel("my-channel", ChannelParams{})
+	assert.EqualError(t, err, "unavailable")
+	upstream.err = nil
+	_, err = cache.Channel("my-channel", ChannelParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, upstream.Calls())
+}
+
+func TestChannelCacheCollapsesConcurrentCalls(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}, release: make(chan struct{})}
+	cache := &ChannelCache{ClientInterface: upstream}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			channel, err := cache.Channel("my-channel", ChannelParams{})
+			assert.NoError(t, err)
+			assert.Equal(t, "my-channel", channel.Name)
+		}()
+	}
+	for cache.Stats().Coalesced < 4 {
+		time.Sleep(time.Millisecond)
+	}
+	close(upstream.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, upstream.Calls())
+	assert.Equal(t, CacheStats{Misses: 1, Coalesced: 4}, cache.Stats())
+}
+
+func TestChannelCacheReleasesCallersWhenFetchPanics(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}, release: make(chan struct{}), panics: true}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute}
+
+	panicked := make(chan interface{})
+	go func() {
+		defer func() { panicked <- recover() }()
+		cache.Channel("my-channel", ChannelParams{})
+	}()
+	for upstream.Calls() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	coalesced := make(chan error)
+	go func() {
+		_, err := cache.Channel("my-channel", ChannelParams{})
+		coalesced <- err
+	}()
+	for cache.Stats().Coalesced < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	close(upstream.release)
+
+	assert.Equal(t, "upstream failure", <-panicked)
+	assert.Equal(t, errChannelCacheFetchPanicked, <-coalesced)
+
+	upstream.mutex.Lock()
+	upstream.release, upstream.panics = nil, false
+	upstream.mutex.Unlock()
+	channel, err := cache.Channel("my-channel", ChannelParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, "my-channel", channel.Name)
+}
+
+func TestChannelCacheInvalidatedByTrigger(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute, ChannelsTTL: time.Minute}
+
+	prefix := "presence-"
+	cache.Channel("presence-room", ChannelParams{})
+	cache.Channel("other", ChannelParams{})
+	cache.Channels(ChannelsParams{FilterByPrefix: &prefix})
+	assert.Equal(t, 3, upstream.Calls())
+
+	assert.NoError(t, cache.Trigger("presence-room", "event", "data"))
+	cache.Channel("presence-room", ChannelParams{})
+	cache.Channel("other", ChannelParams{})
+	cache.Channels(ChannelsParams{FilterByPrefix: &prefix})
+	assert.Equal(t, 5, upstream.Calls())
+}
+
+func TestChannelCacheInvalidatedByWebhook(t *testing.T) {
+	client := &Client{Key: "key", Secret: "secret"}
+	upstream := &countingClient{Client: client}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute}
+
+	cache.Channel("my-channel", ChannelParams{})
+	body := []byte(`{"time_ms":1,"events":[{"name":"channel_vacated","channel":"my-channel"}]}`)
+	header := http.Header{}
+	header.Set("X-Pusher-Key", "key")
+	header.Set("X-Pusher-Signature", hmacSignature(string(body), "secret"))
+	_, err := cache.Webhook(header, body)
+	assert.NoError(t, err)
+
+	cache.Channel("my-channel", ChannelParams{})
+	assert.Equal(t, 2, upstream.Calls())
+}
+
+func TestChannelCacheDiscardsResponsesInvalidatedInFlight(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}, release: make(chan struct{})}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute}
+
+	done := make(chan struct{})
+	go func() {
+		cache.Channel("my-channel", ChannelParams{})
+		close(done)
+	}()
+	for upstream.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cache.Invalidate("my-channel")
+	close(upstream.release)
+	<-done
+
+	cache.Channel("my-channel", ChannelParams{})
+	assert.Equal(t, 2, upstream.Calls())
+}
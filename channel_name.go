@@ -0,0 +1,225 @@
+package pusher
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChannelKind is the kind of a channel, which is given by the prefix of its
+// name.
+type ChannelKind int
+
+const (
+	// PublicChannelKind is the kind of channels without a prefix.
+	PublicChannelKind ChannelKind = iota
+	// PrivateChannelKind is the kind of private- channels.
+	PrivateChannelKind
+	// PresenceChannelKind is the kind of presence- channels.
+	PresenceChannelKind
+	// EncryptedChannelKind is the kind of private-encrypted- channels.
+	EncryptedChannelKind
+	// UserChannelKind is the kind of the channels used by SendToUser.
+	UserChannelKind
+)
+
+const (
+	privateChannelPrefix   = "private-"
+	presenceChannelPrefix  = "presence-"
+	encryptedChannelPrefix = "private-encrypted-"
+	userChannelPrefix      = "#server-to-user-"
+	cacheChannelPrefix     = "cache-"
+)
+
+func (k ChannelKind) String() string {
+	switch k {
+	case PublicChannelKind:
+		return "public"
+	case PrivateChannelKind:
+		return "private"
+	case PresenceChannelKind:
+		return "presence"
+	case EncryptedChannelKind:
+		return "private-encrypted"
+	case UserChannelKind:
+		return "user"
+	}
+	return fmt.Sprintf("ChannelKind(%d)", int(k))
+}
+
+func (k ChannelKind) prefix() string {
+	switch k {
+	case PrivateChannelKind:
+		return privateChannelPrefix
+	case PresenceChannelKind:
+		return presenceChannelPrefix
+	case EncryptedChannelKind:
+		return encryptedChannelPrefix
+	case UserChannelKind:
+		return userChannelPrefix
+	}
+	return ""
+}
+
+/*
+ChannelName is the name of a channel. Names are built with the constructors
+below rather than by concatenating prefixes, and passed to the methods of
+`Client` which take a ChannelName, such as `TriggerChannel`:
+
+	channel := pusher.PresenceChannel("room-" + roomID)
+	err := client.TriggerChannel(channel, "message", data)
+
+They are converted back with `String` for the methods which take a string.
+
+Existing names are classified, and validated, with `ParseChannelName`.
+*/
+type ChannelName string
+
+// PublicChannel returns the name of the public channel with the given ID.
+func PublicChannel(id string) ChannelName {
+	return ChannelName(id)
+}
+
+// PrivateChannel returns the name of the private- channel with the given ID.
+func PrivateChannel(id string) ChannelName {
+	return ChannelName(privateChannelPrefix + id)
+}
+
+// PresenceChannel returns the name of the presence- channel with the given ID.
+func PresenceChannel(id string) ChannelName {
+	return ChannelName(presenceChannelPrefix + id)
+}
+
+// EncryptedChannel returns the name of the private-encrypted- channel with the
+// given ID.
+func EncryptedChannel(id string) ChannelName {
+	return ChannelName(encryptedChannelPrefix + id)
+}
+
+// UserChannel returns the name of the channel which SendToUser triggers on for
+// the given user.
+func UserChannel(userID string) ChannelName {
+	return ChannelName(userChannelPrefix + userID)
+}
+
+/*
+CacheChannel returns the name of the cache channel of the given kind and ID, for
+example "private-cache-prices" for `CacheChannel(pusher.PrivateChannelKind,
+"prices")`. There are no cache channels of `UserChannelKind`, so its names are
+returned unchanged.
+*/
+func CacheChannel(kind ChannelKind, id string) ChannelName {
+	if kind == UserChannelKind {
+		return UserChannel(id)
+	}
+	return ChannelName(kind.prefix() + cacheChannelPrefix + id)
+}
+
+/*
+ParseChannelName classifies and validates a channel name, returning a
+`*ValidationError` if it is invalid. Names of `UserChannelKind` must have a
+valid user ID, and all others must be at most 200 characters of `a-z`, `A-Z`,
+`0-9`, `_`, `-`, `=`, `@`, `,`, `.` and `;`, with something after their prefix.
+
+	channel, err := pusher.ParseChannelName("presence-cache-room")
+	// channel.Kind() => PresenceChannelKind, channel.IsCache() => true, channel.ID() => "room"
+*/
+func ParseChannelName(name string) (ChannelName, error) {
+	channel := ChannelName(name)
+	if err := channel.Validate(); err != nil {
+		return "", err
+	}
+	return channel, nil
+}
+
+// Validate checks the name in the same way as ParseChannelName.
+func (n ChannelName) Validate() error {
+	if violation, ok := channelViolation(string(n), 0); ok {
+		return validationErrorOf([]Violation{violation})
+	}
+	return nil
+}
+
+// Kind returns the kind of the channel, given by the prefix of its name.
+func (n ChannelName) Kind() ChannelKind {
+	name := string(n)
+	switch {
+	case strings.HasPrefix(name, userChannelPrefix):
+		return UserChannelKind
+	case strings.HasPrefix(name, encryptedChannelPrefix):
+		return EncryptedChannelKind
+	case strings.HasPrefix(name, privateChannelPrefix):
+		return PrivateChannelKind
+	case strings.HasPrefix(name, presenceChannelPrefix):
+		return PresenceChannelKind
+	}
+	return PublicChannelKind
+}
+
+// IsCache reports whether the channel is a cache channel.
+func (n ChannelName) IsCache() bool {
+	if n.Kind() == UserChannelKind {
+		return false
+	}
+	return strings.HasPrefix(strings.TrimPrefix(string(n), n.Kind().prefix()), cacheChannelPrefix)
+}
+
+// ID returns the name without the prefixes of its kind and of cache channels,
+// or the user ID for channels of UserChannelKind.
+func (n ChannelName) ID() string {
+	id := strings.TrimPrefix(string(n), n.Kind().prefix())
+	if n.IsCache() {
+		id = strings.TrimPrefix(id, cacheChannelPrefix)
+	}
+	return id
+}
+
+// String returns the name as passed to the methods of Client.
+func (n ChannelName) String() string {
+	return string(n)
+}
+
+// ChannelNames converts names for methods which take several channels, such as
+// TriggerMulti.
+func ChannelNames(names ...ChannelName) []string {
+	channels := make([]string, len(names))
+	for i, name := range names {
+		channels[i] = string(name)
+	}
+	return channels
+}
+
+/*
+TriggerChannel is the same as `client.Trigger`, for a ChannelName.
+
+	err := client.TriggerChannel(pusher.PrivateChannel("orders"), "created", data)
+*/
+func (c *Client) TriggerChannel(channel ChannelName, eventName string, data interface{}) error {
+	return c.Trigger(string(channel), eventName, data)
+}
+
+// TriggerChannelWithParams is the same as `client.TriggerWithParams`, for a
+// ChannelName.
+func (c *Client) TriggerChannelWithParams(channel ChannelName, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	return c.TriggerWithParams(string(channel), eventName, data, params)
+}
+
+// TriggerChannels is the same as `client.TriggerMulti`, for ChannelNames.
+func (c *Client) TriggerChannels(channels []ChannelName, eventName string, data interface{}) error {
+	return c.TriggerMulti(ChannelNames(channels...), eventName, data)
+}
+
+// TriggerChannelsWithParams is the same as `client.TriggerMultiWithParams`, for
+// ChannelNames.
+func (c *Client) TriggerChannelsWithParams(channels []ChannelName, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	return c.TriggerMultiWithParams(ChannelNames(channels...), eventName, data, params)
+}
+
+// ChannelState is the same as `client.Channel`, for a ChannelName.
+func (c *Client) ChannelState(name ChannelName, params ChannelParams) (*Channel, error) {
+	return c.Channel(string(name), params)
+}
+
+// ChannelUsers is the same as `client.GetChannelUsers`, for a ChannelName.
+func (c *Client) ChannelUsers(name ChannelName) (*Users, error) {
+	return c.GetChannelUsers(string(name))
+}
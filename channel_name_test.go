@@ -0,0 +1,117 @@
+package pusher
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestChannelNameConstructors(t *testing.T) {
+	assert.Equal(t, ChannelName("room"), PublicChannel("room"))
+	assert.Equal(t, ChannelName("private-room"), PrivateChannel("room"))
+	assert.Equal(t, ChannelName("presence-room"), PresenceChannel("room"))
+	assert.Equal(t, ChannelName("private-encrypted-room"), EncryptedChannel("room"))
+	assert.Equal(t, ChannelName("#server-to-user-123"), UserChannel("123"))
+	assert.Equal(t, ChannelName("cache-room"), CacheChannel(PublicChannelKind, "room"))
+	assert.Equal(t, ChannelName("private-cache-room"), CacheChannel(PrivateChannelKind, "room"))
+	assert.Equal(t, ChannelName("presence-cache-room"), CacheChannel(PresenceChannelKind, "room"))
+	assert.Equal(t, ChannelName("private-encrypted-cache-room"), CacheChannel(EncryptedChannelKind, "room"))
+	assert.Equal(t, []string{"a", "private-b"}, ChannelNames(PublicChannel("a"), PrivateChannel("b")))
+}
+
+func TestParseChannelName(t *testing.T) {
+	tests := []struct {
+		name  string
+		kind  ChannelKind
+		cache bool
+		id    string
+	}{
+		{"room", PublicChannelKind, false, "room"},
+		{"private-room", PrivateChannelKind, false, "room"},
+		{"presence-room", PresenceChannelKind, false, "room"},
+		{"private-encrypted-room", EncryptedChannelKind, false, "room"},
+		{"#server-to-user-123", UserChannelKind, false, "123"},
+		{"cache-room", PublicChannelKind, true, "room"},
+		{"private-cache-room", PrivateChannelKind, true, "room"},
+		{"presence-cache-room", PresenceChannelKind, true, "room"},
+		{"private-encrypted-cache-room", EncryptedChannelKind, true, "room"},
+	}
+	for _, test := range tests {
+		channel, err := ParseChannelName(test.name)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.kind, channel.Kind(), test.name)
+		assert.Equal(t, test.cache, channel.IsCache(), test.name)
+		assert.Equal(t, test.id, channel.ID(), test.name)
+		assert.Equal(t, test.name, channel.String(), test.name)
+	}
+}
+
+func TestParseChannelNameInvalid(t *testing.T) {
+	_, err := ParseChannelName("hello world")
+	assert.EqualError(t, err, "Invalid names: channel 'hello world' has the illegal character ' ' at position 5")
+
+	_, err = ParseChannelName(strings.Repeat("a", 201))
+	assert.Error(t, err)
+
+	_, err = ParseChannelName("")
+	assert.Error(t, err)
+
+	_, err = ParseChannelName("#server-to-user-")
+	assert.EqualError(t, err, "Invalid names: channel '#server-to-user-' has nothing after its prefix")
+
+	_, err = ParseChannelName("presence-")
+	assert.Equal(t, RuleMissingID, err.(*ValidationError).Violations[0].Rule)
+}
+
+func TestChannelKindString(t *testing.T) {
+	assert.Equal(t, "presence", PresenceChannelKind.String())
+	assert.Equal(t, "private-encrypted", EncryptedChannelKind.String())
+	assert.Equal(t, "ChannelKind(42)", ChannelKind(42).String())
+}
+
+func TestClientMethodsTakingChannelNames(t *testing.T) {
+	var bodies []map[string]interface{}
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		paths = append(paths, req.URL.Path)
+		if req.Method == "POST" {
+			raw, _ := ioutil.ReadAll(req.Body)
+			var body map[string]interface{}
+			json.Unmarshal(raw, &body)
+			bodies = append(bodies, body)
+		}
+		res.WriteHeader(200)
+		if strings.HasSuffix(req.URL.Path, "/users") {
+			fmt.Fprintf(res, `{"users":[{"id":"1"}]}`)
+			return
+		}
+		fmt.Fprintf(res, `{"occupied":true}`)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+
+	assert.NoError(t, client.TriggerChannel(PrivateChannel("a"), "event", "data"))
+	assert.NoError(t, client.TriggerChannels([]ChannelName{PublicChannel("b"), PresenceChannel("c")}, "event", "data"))
+	assert.Equal(t, []interface{}{"private-a"}, bodies[0]["channels"])
+	assert.Equal(t, []interface{}{"b", "presence-c"}, bodies[1]["channels"])
+
+	channel, err := client.ChannelState(PresenceChannel("c"), ChannelParams{})
+	assert.NoError(t, err)
+	assert.True(t, channel.Occupied)
+	users, err := client.ChannelUsers(PresenceChannel("c"))
+	assert.NoError(t, err)
+	assert.Equal(t, []User{{ID: "1"}}, users.List)
+	assert.Equal(t, []string{"/apps/id/events", "/apps/id/events", "/apps/id/channels/presence-c", "/apps/id/channels/presence-c/users"}, paths)
+
+	err = client.TriggerChannel(PrivateChannel("bad name"), "event", "data")
+	assert.IsType(t, &ValidationError{}, err)
+}
@@ -1,6 +1,7 @@
 package pusher
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 )
@@ -46,19 +48,27 @@ Changing the `pusher.Client`'s `Host` property will make sure requests are sent
 to your specified host.
 
 	client.Host = "foo.bar.com" // by default this is "api.pusherapp.com".
+
+Event data other than strings and byte slices is encoded as JSON, unless the
+`Codec` property is set to another `pusher.Codec`:
+
+	client.Codec = pushercodec.Msgpack{}
 */
 type Client struct {
-	AppID                        string
-	Key                          string
-	Secret                       string
-	Host                         string // host or host:port pair
-	Secure                       bool   // true for HTTPS
-	Cluster                      string
-	HTTPClient                   *http.Client
-	EncryptionMasterKey          string  // deprecated
-	EncryptionMasterKeyBase64    string  // for E2E
-	OverrideMaxMessagePayloadKB  int     // set the agreed Pusher message limit increase
-	validatedEncryptionMasterKey *[]byte // parsed key for use
+	AppID                         string
+	Key                           string
+	Secret                        string
+	Host                          string // host or host:port pair
+	Secure                        bool   // true for HTTPS
+	Cluster                       string
+	HTTPClient                    *http.Client
+	EncryptionMasterKey           string                // deprecated
+	EncryptionMasterKeyBase64     string                // for E2E
+	EncryptionMasterKeys          []EncryptionMasterKey // for E2E, with key rotation
+	OverrideMaxMessagePayloadKB   int                   // set the agreed Pusher message limit increase
+	AllowClientEvents             bool                  // allow triggering client- events, to relay them
+	Codec                         Codec                 // encodes event data, JSONCodec by default
+	validatedEncryptionMasterKeys *[]masterKey          // parsed keys for use
 }
 
 /*
@@ -134,7 +144,7 @@ contain only characters which are alphanumeric, `_` or `-`` and have
 to be at most 200 characters long. Event name can be at most 200 characters long too.
 
 Pass in the channel's name, the event's name, and a data payload. The data payload must
-be marshallable into JSON.
+be marshallable into JSON, or encodable by the client's `Codec` if it is set.
 
 	data := map[string]string{"hello": "world"}
 	client.Trigger("greeting_channel", "say_hello", data)
@@ -260,24 +270,153 @@ be marshallable into JSON.
 	client.SendToUser("user123", "say_hello", data)
 */
 func (c *Client) SendToUser(userId string, eventName string, data interface{}) error {
+	_, err := c.SendToUserWithParams(userId, eventName, data, TriggerParams{})
+	return err
+}
+
+/*
+SendToUserWithParams is the same as `client.SendToUser`, except it allows
+additional parameters to be specified in the same way as
+`client.TriggerWithParams`, for example to exclude the socket which originated
+the event.
+
+	socketID := "1234.12"
+	params := pusher.TriggerParams{SocketID: &socketID}
+	_, err := client.SendToUserWithParams("user123", "say_hello", data, params)
+*/
+func (c *Client) SendToUserWithParams(userId string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
 	if !validUserId(userId) {
+		return nil, fmt.Errorf("User id '%s' is invalid", userId)
+	}
+	if violation, ok := eventViolation(eventName, 0, c.AllowClientEvents); ok {
+		return nil, validationErrorOf([]Violation{violation})
+	}
+	return c.trigger([]string{UserChannel(userId).String()}, eventName, data, params)
+}
+
+/*
+SendToUsers triggers an event to several users. The users are packed into as
+few requests as possible, each of up to 100 users. Every user is attempted, and
+a `UserErrors` is returned for those the event could not be sent to.
+
+	err := client.SendToUsers([]string{"user1", "user2"}, "say_hello", data)
+	if userErrors, ok := err.(pusher.UserErrors); ok {
+		retry(userErrors)
+	}
+*/
+func (c *Client) SendToUsers(userIds []string, eventName string, data interface{}) error {
+	if violation, ok := eventViolation(eventName, 0, c.AllowClientEvents); ok {
+		return validationErrorOf([]Violation{violation})
+	}
+	userErrors := UserErrors{}
+	var validUserIds []string
+	for _, userId := range userIds {
+		if validUserId(userId) {
+			validUserIds = append(validUserIds, userId)
+		} else {
+			userErrors[userId] = fmt.Errorf("User id '%s' is invalid", userId)
+		}
+	}
+
+	for start := 0; start < len(validUserIds); start += maxTriggerableChannels {
+		end := start + maxTriggerableChannels
+		if end > len(validUserIds) {
+			end = len(validUserIds)
+		}
+		channels := make([]string, end-start)
+		for i, userId := range validUserIds[start:end] {
+			channels[i] = UserChannel(userId).String()
+		}
+		if _, err := c.trigger(channels, eventName, data, TriggerParams{}); err != nil {
+			for _, userId := range validUserIds[start:end] {
+				userErrors[userId] = err
+			}
+		}
+	}
+
+	if len(userErrors) > 0 {
+		return userErrors
+	}
+	return nil
+}
+
+/*
+UserErrors is returned by methods acting on several users when some of them
+failed. It maps the IDs of those users to their error.
+*/
+type UserErrors map[string]error
+
+func (e UserErrors) Error() string {
+	userIds := make([]string, 0, len(e))
+	for userId := range e {
+		userIds = append(userIds, userId)
+	}
+	sort.Strings(userIds)
+
+	messages := make([]string, len(userIds))
+	for i, userId := range userIds {
+		messages[i] = fmt.Sprintf("%s: %s", userId, e[userId])
+	}
+	return fmt.Sprintf("Failed for %d users: %s", len(e), strings.Join(messages, "; "))
+}
+
+/*
+TerminateUserConnections disconnects every connection of a user, for example
+when banning them or revoking their session. User IDs containing `/`, `?`, `#`,
+`%` or `..` are rejected, since they would change the path of the request.
+
+	err := client.TerminateUserConnections(ctx, "user123")
+*/
+func (c *Client) TerminateUserConnections(ctx context.Context, userId string) error {
+	if !validUserIdPath(userId) {
 		return fmt.Errorf("User id '%s' is invalid", userId)
 	}
-	_, err := c.trigger([]string{"#server-to-user-" + userId}, eventName, data, TriggerParams{})
+	_, err := c.Do(ctx, "POST", fmt.Sprintf("/users/%s/terminate_connections", userId), nil, []byte("{}"))
 	return err
 }
 
+/*
+TerminateUsersConnections is the same as `client.TerminateUserConnections`, for
+several users. Every user is attempted, and a `UserErrors` is returned for
+those whose connections could not be terminated.
+
+	err := client.TerminateUsersConnections(ctx, []string{"user1", "user2"})
+	if userErrors, ok := err.(pusher.UserErrors); ok {
+		retry(userErrors)
+	}
+*/
+func (c *Client) TerminateUsersConnections(ctx context.Context, userIds []string) error {
+	userErrors := UserErrors{}
+	for _, userId := range userIds {
+		if err := c.TerminateUserConnections(ctx, userId); err != nil {
+			userErrors[userId] = err
+		}
+	}
+	if len(userErrors) > 0 {
+		return userErrors
+	}
+	return nil
+}
+
 func (c *Client) validateChannelsAndTrigger(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	return c.validateChannelsAndTriggerWithContext(context.Background(), channels, eventName, data, params)
+}
+
+func (c *Client) validateChannelsAndTriggerWithContext(ctx context.Context, channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
 	if len(channels) > maxTriggerableChannels {
 		return nil, fmt.Errorf("You cannot trigger on more than %d channels at once", maxTriggerableChannels)
 	}
-	if !channelsAreValid(channels) {
-		return nil, errors.New("At least one of your channels' names are invalid")
+	if err := validateTrigger(channels, eventName, c.AllowClientEvents); err != nil {
+		return nil, err
 	}
-	return c.trigger(channels, eventName, data, params)
+	return c.triggerWithContext(ctx, channels, eventName, data, params)
 }
 
 func (c *Client) trigger(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	return c.triggerWithContext(context.Background(), channels, eventName, data, params)
+}
+
+func (c *Client) triggerWithContext(ctx context.Context, channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
 	hasEncryptedChannel := false
 	for _, channel := range channels {
 		if isEncryptedChannel(channel) {
@@ -297,7 +436,7 @@ func (c *Client) trigger(channels []string, eventName string, data interface{},
 		return nil, err
 	}
 
-	payload, err := encodeTriggerBody(channels, eventName, data, params.toMap(), masterKey, c.OverrideMaxMessagePayloadKB)
+	payload, err := encodeTriggerBody(channels, eventName, data, params.toMap(), masterKey, c.OverrideMaxMessagePayloadKB, c.Codec)
 	if err != nil {
 		return nil, err
 	}
@@ -306,7 +445,7 @@ func (c *Client) trigger(channels []string, eventName string, data interface{},
 	if err != nil {
 		return nil, err
 	}
-	response, err := c.request("POST", triggerURL, payload)
+	response, err := requestWithContext(ctx, c.requestClient(), "POST", triggerURL, payload)
 	if err != nil {
 		return nil, err
 	}
@@ -337,12 +476,12 @@ TriggerBatch triggers multiple events on multiple channels in a single call:
 	})
 */
 func (c *Client) TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error) {
+	if err := validateBatch(batch, c.AllowClientEvents); err != nil {
+		return nil, err
+	}
 	hasEncryptedChannel := false
-	// validate every channel name and every sockedID (if present) in batch
+	// validate every sockedID (if present) in batch
 	for _, event := range batch {
-		if !validChannel(event.Channel) {
-			return nil, fmt.Errorf("The channel named %s has a non-valid name", event.Channel)
-		}
 		if err := validateSocketID(event.SocketID); err != nil {
 			return nil, err
 		}
@@ -355,7 +494,7 @@ func (c *Client) TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error)
 		return nil, keyErr
 	}
 
-	payload, err := encodeTriggerBatchBody(batch, masterKey, c.OverrideMaxMessagePayloadKB)
+	payload, err := encodeTriggerBatchBody(batch, masterKey, c.OverrideMaxMessagePayloadKB, c.Codec)
 	if err != nil {
 		return nil, err
 	}
@@ -382,6 +521,10 @@ type ChannelsParams struct {
 	// of users subscribed to a presence-channel. Pass in `nil` if you do
 	// not wish to specify any query attributes.
 	Info *string
+	// InfoAttributes are the attributes to return for each channel, in
+	// addition to those in Info. InfoUserCount requires FilterByPrefix to
+	// start with "presence-".
+	InfoAttributes []InfoAttribute
 }
 
 func (params ChannelsParams) toMap() map[string]string {
@@ -389,8 +532,8 @@ func (params ChannelsParams) toMap() map[string]string {
 	if params.FilterByPrefix != nil {
 		m["filter_by_prefix"] = *params.FilterByPrefix
 	}
-	if params.Info != nil {
-		m["info"] = *params.Info
+	if info := joinInfo(params.Info, params.InfoAttributes); info != "" {
+		m["info"] = info
 	}
 	return m
 }
@@ -399,13 +542,22 @@ func (params ChannelsParams) toMap() map[string]string {
 Channels returns a list of all the channels in an application.
 
 	prefixFilter := "presence-"
-	attributes := "user_count"
-	params := pusher.ChannelsParams{FilterByPrefix: &prefixFilter, Info: &attributes}
+	params := pusher.ChannelsParams{
+		FilterByPrefix: &prefixFilter,
+		InfoAttributes: []pusher.InfoAttribute{pusher.InfoUserCount, pusher.InfoSubscriptionCount},
+	}
 	channels, err := client.Channels(params)
 
-	//channels=> &{Channels:map[presence-chatroom:{UserCount:4} presence-notifications:{UserCount:31}  ]}
+	//channels=> &{Channels:map[presence-chatroom:{UserCount:4 SubscriptionCount:5} presence-notifications:{UserCount:31 SubscriptionCount:40}]}
 */
 func (c *Client) Channels(params ChannelsParams) (*ChannelsList, error) {
+	prefix := ""
+	if params.FilterByPrefix != nil {
+		prefix = *params.FilterByPrefix
+	}
+	if err := validateInfoAttributes(params.InfoAttributes, prefix); err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("/apps/%s/channels", c.AppID)
 	u, err := createRequestURL("GET", c.Host, path, c.Key, c.Secret, authTimestamp(), c.Secure, nil, params.toMap(), c.Cluster)
 	if err != nil {
@@ -428,12 +580,15 @@ type ChannelParams struct {
 	// contact us at http://support.pusher.com if you wish to enable this.
 	// Pass in `nil` if you do not wish to specify any query attributes.
 	Info *string
+	// InfoAttributes are the attributes to return, in addition to those in
+	// Info. InfoUserCount is only allowed for presence-channels.
+	InfoAttributes []InfoAttribute
 }
 
 func (params ChannelParams) toMap() map[string]string {
 	m := make(map[string]string)
-	if params.Info != nil {
-		m["info"] = *params.Info
+	if info := joinInfo(params.Info, params.InfoAttributes); info != "" {
+		m["info"] = info
 	}
 	return m
 }
@@ -448,6 +603,9 @@ Channel allows you to get the state of a single channel.
 	//channel=> &{Name:presence-chatroom Occupied:true UserCount:42 SubscriptionCount:42}
 */
 func (c *Client) Channel(name string, params ChannelParams) (*Channel, error) {
+	if err := validateInfoAttributes(params.InfoAttributes, name); err != nil {
+		return nil, err
+	}
 	path := fmt.Sprintf("/apps/%s/channels/%s", c.AppID, name)
 	u, err := createRequestURL("GET", c.Host, path, c.Key, c.Secret, authTimestamp(), c.Secure, nil, params.toMap(), c.Cluster)
 	if err != nil {
@@ -481,6 +639,48 @@ func (c *Client) GetChannelUsers(name string) (*Users, error) {
 	return unmarshalledChannelUsers(response)
 }
 
+/*
+Do makes a signed request to any endpoint of the HTTP API, for endpoints this
+library does not wrap yet. The `path` is relative to the application, so
+`"/channels"` requests `/apps/{app_id}/channels`. The body, if not nil, is sent
+as is when it is a `[]byte` or a `string`, and marshalled into JSON otherwise.
+
+The request goes through the same host resolution, signing, headers and
+error handling as the other methods: a non-2xx response is returned as a
+`*RequestError`. The raw response body is returned.
+
+	response, err := client.Do(ctx, "GET", "/channels", map[string]string{"filter_by_prefix": "presence-"}, nil)
+*/
+func (c *Client) Do(ctx context.Context, method, path string, query map[string]string, body interface{}) ([]byte, error) {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = encodeEventData(body, nil); err != nil {
+			return nil, err
+		}
+	}
+	u, err := c.Signer().SignURL(method, fmt.Sprintf("/apps/%s%s", c.AppID, path), query, payload)
+	if err != nil {
+		return nil, err
+	}
+	return requestWithContext(ctx, c.requestClient(), method, u, payload)
+}
+
+/*
+DoInto is the same as `client.Do`, except it unmarshals the JSON response into
+`result`.
+
+	var channel pusher.Channel
+	err := client.DoInto(ctx, "GET", "/channels/presence-room", nil, nil, &channel)
+*/
+func (c *Client) DoInto(ctx context.Context, method, path string, query map[string]string, body interface{}, result interface{}) error {
+	response, err := c.Do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response, result)
+}
+
 /*
 AuthenticateUser allows you to authenticate a user's connection.
 It returns an authentication signature to send back to the client
@@ -653,12 +853,11 @@ func (c *Client) authorizeChannel(params []byte, member *MemberData) (response [
 	var _response map[string]string
 
 	if isEncryptedChannel(channelName) {
-		masterKey, err := c.encryptionMasterKey()
+		sharedSecret, err := c.SharedSecretForChannel(channelName)
 		if err != nil {
 			return nil, err
 		}
-		sharedSecret := generateSharedSecret(channelName, masterKey)
-		sharedSecretB64 := base64.StdEncoding.EncodeToString(sharedSecret[:])
+		sharedSecretB64 := base64.StdEncoding.EncodeToString(sharedSecret)
 		_response = createAuthMap(c.Key, c.Secret, stringToSign, sharedSecretB64)
 	} else {
 		_response = createAuthMap(c.Key, c.Secret, stringToSign, "")
@@ -672,6 +871,69 @@ func (c *Client) authorizeChannel(params []byte, member *MemberData) (response [
 	return
 }
 
+/*
+SharedSecretForChannel returns the secret which clients use to decrypt events of
+a private-encrypted- channel. It is derived from the primary encryption master
+key, and is what `AuthorizePrivateChannel` sends, base64 encoded, to clients.
+*/
+func (c *Client) SharedSecretForChannel(channel string) ([]byte, error) {
+	if !isEncryptedChannel(channel) {
+		return nil, fmt.Errorf("Channel '%s' is not an encrypted channel", channel)
+	}
+	masterKey, err := c.encryptionMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	sharedSecret := generateSharedSecret(channel, masterKey)
+	return sharedSecret[:], nil
+}
+
+/*
+EncryptForChannel encrypts data for a private-encrypted- channel with the primary
+encryption master key, in the same way as events triggered on that channel.
+This is useful for data delivered to clients by other means than `Trigger`.
+The data is encoded in the same way as for `Trigger`.
+
+	message, err := client.EncryptForChannel("private-encrypted-chat", data)
+*/
+func (c *Client) EncryptForChannel(channel string, data interface{}) (*EncryptedMessage, error) {
+	if !isEncryptedChannel(channel) {
+		return nil, fmt.Errorf("Channel '%s' is not an encrypted channel", channel)
+	}
+	masterKey, err := c.encryptionMasterKey()
+	if err != nil {
+		return nil, err
+	}
+	dataBytes, err := encodeEventData(data, c.Codec)
+	if err != nil {
+		return nil, err
+	}
+	encryptedMessage := encryptMessage(channel, dataBytes, masterKey)
+	return &encryptedMessage, nil
+}
+
+/*
+DecryptForChannel decrypts a message of a private-encrypted- channel, such as
+an archived event, trying every configured encryption master key. It returns
+`ErrInvalidNonce` or `ErrInvalidCiphertext` for malformed messages, and
+`ErrDecryptionFailed` when no key decrypts the message.
+
+	var message pusher.EncryptedMessage
+	json.Unmarshal(archived, &message)
+	data, err := client.DecryptForChannel("private-encrypted-chat", message)
+*/
+func (c *Client) DecryptForChannel(channel string, message EncryptedMessage) ([]byte, error) {
+	if !isEncryptedChannel(channel) {
+		return nil, fmt.Errorf("Channel '%s' is not an encrypted channel", channel)
+	}
+	masterKeys, err := c.encryptionMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := decryptMessage(channel, message, masterKeys)
+	return data, err
+}
+
 /*
 Webhook allows you to check that a Webhook you receive is indeed from Pusher, by
 checking the token and authentication signature in the header of the request. On
@@ -685,6 +947,10 @@ If the webhook is valid, a `*pusher.Webhook* will be returned, and the `err`
 value will be nil. If it is invalid, the first return value will be nil, and an
 error will be passed.
 
+Events of private-encrypted- channels are decrypted. An event which cannot be
+decrypted keeps its encrypted data and carries the reason in its
+`DecryptionError`, without affecting the other events of the webhook.
+
 	func pusherWebhook(res http.ResponseWriter, req *http.Request) {
 
 		body, _ := ioutil.ReadAll(req.Body)
@@ -698,11 +964,35 @@ error will be passed.
 	}
 */
 func (c *Client) Webhook(header http.Header, body []byte) (*Webhook, error) {
+	webhook, _, err := c.verifyWebhook(header, body, []WebhookCredentials{{Key: c.Key, Secret: c.Secret}}, nil)
+	return webhook, err
+}
+
+/*
+DecodeClientEvent decodes the data of a client event received by a webhook into
+v, with the client's `Codec`, so that events are decoded in the same way as the
+client encodes them. A string or byte slice v receives the data as is. It
+returns the event's `DecryptionError`, if any.
+
+	var message ChatMessage
+	err := client.DecodeClientEvent(event, &message)
+*/
+func (c *Client) DecodeClientEvent(event ClientEvent, v interface{}) error {
+	if event.DecryptionError != nil {
+		return event.DecryptionError
+	}
+	return decodeEventData(event.Data, v, c.Codec)
+}
+
+func (c *Client) verifyWebhook(header http.Header, body []byte, credentials []WebhookCredentials, extraMasterKeys []EncryptionMasterKey) (*Webhook, WebhookCredentials, error) {
 	for _, token := range header["X-Pusher-Key"] {
-		if token == c.Key && checkSignature(header.Get("X-Pusher-Signature"), c.Secret, body) {
+		for _, credential := range credentials {
+			if token != credential.Key || !checkSignature(header.Get("X-Pusher-Signature"), credential.Secret, body) {
+				continue
+			}
 			unmarshalledWebhooks, err := unmarshalledWebhook(body)
 			if err != nil {
-				return nil, err
+				return nil, credential, err
 			}
 
 			hasEncryptedChannel := false
@@ -711,22 +1001,96 @@ func (c *Client) Webhook(header http.Header, body []byte) (*Webhook, error) {
 					hasEncryptedChannel = true
 				}
 			}
-			masterKey, keyErr := c.encryptionMasterKey()
+			masterKeys, keyErr := c.decryptionMasterKeys(extraMasterKeys)
 			if hasEncryptedChannel && keyErr != nil {
-				return nil, keyErr
+				return nil, credential, keyErr
 			}
 
-			return decryptEvents(*unmarshalledWebhooks, masterKey)
+			return decryptEvents(*unmarshalledWebhooks, masterKeys), credential, nil
+		}
+	}
+	return nil, WebhookCredentials{}, errors.New("Invalid webhook")
+}
+
+// decryptionMasterKeys returns the client's master keys, if any, followed by
+// extraMasterKeys.
+func (c *Client) decryptionMasterKeys(extraMasterKeys []EncryptionMasterKey) ([]masterKey, error) {
+	var keys []masterKey
+	if c.EncryptionMasterKey != "" || c.EncryptionMasterKeyBase64 != "" || len(c.EncryptionMasterKeys) > 0 || len(extraMasterKeys) == 0 {
+		clientKeys, err := c.encryptionMasterKeys()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, clientKeys...)
+	}
+	for _, extraMasterKey := range extraMasterKeys {
+		key, err := extraMasterKey.parse()
+		if err != nil {
+			return nil, err
 		}
+		keys = append(keys, key)
 	}
-	return nil, errors.New("Invalid webhook")
+	return keys, nil
 }
 
+// encryptionMasterKey returns the primary master key, which is used to encrypt
+// events and to generate shared secrets.
 func (c *Client) encryptionMasterKey() ([]byte, error) {
-	if c.validatedEncryptionMasterKey != nil {
-		return *(c.validatedEncryptionMasterKey), nil
+	keys, err := c.encryptionMasterKeys()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, key := range keys {
+		if !key.activeFrom.After(now) {
+			return key.key, nil
+		}
 	}
+	return nil, errors.New("None of the encryption master keys is active yet")
+}
 
+// encryptionMasterKeys returns every configured master key, the most recently
+// activated first.
+func (c *Client) encryptionMasterKeys() ([]masterKey, error) {
+	if c.validatedEncryptionMasterKeys != nil {
+		return *(c.validatedEncryptionMasterKeys), nil
+	}
+
+	var keys []masterKey
+	if c.EncryptionMasterKey != "" || c.EncryptionMasterKeyBase64 != "" {
+		keyBytes, err := c.singleEncryptionMasterKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, masterKey{key: keyBytes})
+	}
+
+	ids := make(map[string]bool)
+	for _, encryptionMasterKey := range c.EncryptionMasterKeys {
+		if ids[encryptionMasterKey.ID] {
+			return nil, fmt.Errorf("Encryption master key '%s' is specified more than once", encryptionMasterKey.ID)
+		}
+		ids[encryptionMasterKey.ID] = true
+
+		key, err := encryptionMasterKey.parse()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("No master encryption key supplied")
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activeFrom.After(keys[j].activeFrom)
+	})
+
+	c.validatedEncryptionMasterKeys = &keys
+	return keys, nil
+}
+
+func (c *Client) singleEncryptionMasterKey() ([]byte, error) {
 	if c.EncryptionMasterKey != "" && c.EncryptionMasterKeyBase64 != "" {
 		return nil, errors.New("Do not specify both EncryptionMasterKey and EncryptionMasterKeyBase64. EncryptionMasterKey is deprecated, specify only EncryptionMasterKeyBase64")
 	}
@@ -735,24 +1099,20 @@ func (c *Client) encryptionMasterKey() ([]byte, error) {
 		if len(c.EncryptionMasterKey) != 32 {
 			return nil, errors.New("EncryptionMasterKey must be 32 bytes. It is also deprecated, use EncryptionMasterKeyBase64")
 		}
-
-		keyBytes := []byte(c.EncryptionMasterKey)
-		c.validatedEncryptionMasterKey = &keyBytes
-		return keyBytes, nil
+		return []byte(c.EncryptionMasterKey), nil
 	}
 
-	if c.EncryptionMasterKeyBase64 != "" {
-		keyBytes, err := base64.StdEncoding.DecodeString(c.EncryptionMasterKeyBase64)
-		if err != nil {
-			return nil, errors.New("EncryptionMasterKeyBase64 must be valid base64")
-		}
-		if len(keyBytes) != 32 {
-			return nil, errors.New("EncryptionMasterKeyBase64 must encode 32 bytes")
-		}
-
-		c.validatedEncryptionMasterKey = &keyBytes
-		return keyBytes, nil
+	keyBytes, err := base64.StdEncoding.DecodeString(c.EncryptionMasterKeyBase64)
+	if err != nil {
+		return nil, errors.New("EncryptionMasterKeyBase64 must be valid base64")
 	}
+	if len(keyBytes) != 32 {
+		return nil, errors.New("EncryptionMasterKeyBase64 must encode 32 bytes")
+	}
+	return keyBytes, nil
+}
 
-	return nil, errors.New("No master encryption key supplied")
+// eventCodec returns the Codec of event data, or nil for JSON.
+func (c *Client) eventCodec() Codec {
+	return c.Codec
 }
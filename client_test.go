@@ -1,6 +1,7 @@
 package pusher
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -309,6 +310,42 @@ func TestGetChannelSuccess(t *testing.T) {
 	assert.Equal(t, expected, channel)
 }
 
+func TestGetChannelsWithInfoAttributes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "user_count,subscription_count", req.URL.Query().Get("info"))
+		res.WriteHeader(200)
+		fmt.Fprintf(res, `{"channels":{"presence-room":{"user_count":2,"subscription_count":3}}}`)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	prefix := "presence-"
+	channels, err := client.Channels(ChannelsParams{
+		FilterByPrefix: &prefix,
+		InfoAttributes: []InfoAttribute{InfoUserCount, InfoSubscriptionCount},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, ChannelListItem{UserCount: 2, SubscriptionCount: 3}, channels.Channels["presence-room"])
+}
+
+func TestInfoAttributesValidation(t *testing.T) {
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: "localhost:1"}
+
+	_, err := client.Channels(ChannelsParams{InfoAttributes: []InfoAttribute{InfoUserCount}})
+	assert.EqualError(t, err, "The user_count attribute is only available for presence-channels, not ''")
+
+	prefix := "private-"
+	_, err = client.Channels(ChannelsParams{FilterByPrefix: &prefix, InfoAttributes: []InfoAttribute{InfoUserCount}})
+	assert.EqualError(t, err, "The user_count attribute is only available for presence-channels, not 'private-'")
+
+	_, err = client.Channel("my-channel", ChannelParams{InfoAttributes: []InfoAttribute{InfoUserCount}})
+	assert.EqualError(t, err, "The user_count attribute is only available for presence-channels, not 'my-channel'")
+
+	_, err = client.Channel("my-channel", ChannelParams{InfoAttributes: []InfoAttribute{"occupied"}})
+	assert.EqualError(t, err, "Unknown info attribute 'occupied'")
+}
+
 func TestGetChannelUserSuccess(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
 		res.WriteHeader(200)
@@ -769,9 +806,14 @@ func TestChannelFormatValidation(t *testing.T) {
 
 	err2 := client.Trigger(channel2, "yolo", "not 19 forever")
 
-	assert.EqualError(t, err1, "At least one of your channels' names are invalid")
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "channel", Name: channel1, Index: 0, Rule: RuleCharset, Position: 4},
+	}}, err1)
+	assert.EqualError(t, err1, "Invalid names: channel 'w000^$$£@@@' has the illegal character '^' at position 4")
 
-	assert.EqualError(t, err2, "At least one of your channels' names are invalid")
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "channel", Name: channel2, Index: 0, Rule: RuleTooLong, Position: 200},
+	}}, err2)
 
 }
 
@@ -841,3 +883,183 @@ func TestInitialisationFromENV(t *testing.T) {
 	expectedClient := &Client{Key: "feaf18a411d3cb9216ee", Secret: "fec81108d90e1898e17a", AppID: "104060", Host: "api.pusherapp.com"}
 	assert.Equal(t, expectedClient, client)
 }
+
+func TestDoSuccessCase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "/apps/id/some/new_endpoint", req.URL.Path)
+		assert.Equal(t, "1", req.URL.Query().Get("extra"))
+		assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		assert.NoError(t, VerifyRequest(req, map[string]string{"key": "secret"}))
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, `{"hello":"world"}`, string(body))
+		fmt.Fprintf(res, `{"ok":true}`)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	response, err := client.Do(context.Background(), "POST", "/some/new_endpoint", map[string]string{"extra": "1"}, map[string]string{"hello": "world"})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ok":true}`, string(response))
+
+	var result struct {
+		OK bool `json:"ok"`
+	}
+	err = client.DoInto(context.Background(), "POST", "/some/new_endpoint", map[string]string{"extra": "1"}, []byte(`{"hello":"world"}`), &result)
+	assert.NoError(t, err)
+	assert.True(t, result.OK)
+}
+
+func TestDoErrorResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(404)
+		fmt.Fprintf(res, "Not found")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	_, err := client.Do(context.Background(), "GET", "/nothing", nil, nil)
+	assert.EqualError(t, err, "Status Code: 404 - Not found")
+	requestError, ok := err.(*RequestError)
+	assert.True(t, ok)
+	assert.Equal(t, 404, requestError.StatusCode)
+}
+
+func TestDoCancelledContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		t.Fatal("No request should reach the API")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.Do(ctx, "GET", "/channels", nil, nil)
+	assert.Error(t, err)
+}
+
+func TestTerminateUserConnectionsSuccessCase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, "/apps/id/users/123456/terminate_connections", req.URL.Path)
+		assert.NoError(t, VerifyRequest(req, map[string]string{"key": "secret"}))
+		res.WriteHeader(200)
+		fmt.Fprintf(res, "{}")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	err := client.TerminateUserConnections(context.Background(), "123456")
+	assert.NoError(t, err)
+}
+
+func TestTerminateUserConnectionsRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		t.Fatal("No request should reach the API")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	err := client.TerminateUserConnections(context.Background(), "")
+	assert.EqualError(t, err, "User id '' is invalid")
+
+	for _, userId := range []string{"a?b=1", "../../channels/x", "a/b", "a#b", "a%2Fb", ".."} {
+		err := client.TerminateUserConnections(context.Background(), userId)
+		assert.EqualError(t, err, fmt.Sprintf("User id '%s' is invalid", userId))
+	}
+}
+
+func TestTerminateUsersConnectionsReportsFailures(t *testing.T) {
+	var terminated []string
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/apps/id/users/2/terminate_connections" {
+			res.WriteHeader(500)
+			fmt.Fprintf(res, "oops")
+			return
+		}
+		terminated = append(terminated, req.URL.Path)
+		fmt.Fprintf(res, "{}")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	err := client.TerminateUsersConnections(context.Background(), []string{"1", "2", "", "3"})
+	assert.Equal(t, []string{"/apps/id/users/1/terminate_connections", "/apps/id/users/3/terminate_connections"}, terminated)
+
+	userErrors, ok := err.(UserErrors)
+	assert.True(t, ok)
+	assert.Len(t, userErrors, 2)
+	assert.EqualError(t, userErrors["2"], "Status Code: 500 - oops")
+	assert.EqualError(t, err, "Failed for 2 users: : User id '' is invalid; 2: Status Code: 500 - oops")
+
+	assert.NoError(t, client.TerminateUsersConnections(context.Background(), []string{"1"}))
+}
+
+func TestSendToUserWithParamsSuccessCase(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(200)
+		fmt.Fprintf(res, `{"channels":{"#server-to-user-123456":{"subscription_count":2}}}`)
+
+		expectedBody := map[string]interface{}{"name": "test", "channels": []interface{}{"#server-to-user-123456"}, "data": "yolo", "socket_id": "1234.12", "info": "subscription_count"}
+		var actualBody map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&actualBody))
+		assert.Equal(t, expectedBody, actualBody)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	socketID := "1234.12"
+	info := "subscription_count"
+	channels, err := client.SendToUserWithParams("123456", "test", "yolo", TriggerParams{SocketID: &socketID, Info: &info})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *channels.Channels["#server-to-user-123456"].SubscriptionCount)
+
+	_, err = client.SendToUserWithParams("", "test", "yolo", TriggerParams{})
+	assert.EqualError(t, err, "User id '' is invalid")
+}
+
+func TestSendToUsersPacksRequests(t *testing.T) {
+	var requests [][]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		var body map[string]interface{}
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		channels := body["channels"].([]interface{})
+		requests = append(requests, channels)
+		if channels[0] == "#server-to-user-100" {
+			res.WriteHeader(500)
+			fmt.Fprintf(res, "oops")
+			return
+		}
+		fmt.Fprintf(res, "{}")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	userIds := []string{""}
+	for i := 0; i < 150; i++ {
+		userIds = append(userIds, fmt.Sprint(i))
+	}
+	err := client.SendToUsers(userIds, "test", "yolo")
+
+	assert.Len(t, requests, 2)
+	assert.Len(t, requests[0], 100)
+	assert.Len(t, requests[1], 50)
+	userErrors, ok := err.(UserErrors)
+	assert.True(t, ok)
+	assert.Len(t, userErrors, 51)
+	assert.EqualError(t, userErrors[""], "User id '' is invalid")
+	assert.EqualError(t, userErrors["149"], "Status Code: 500 - oops")
+	assert.NotContains(t, userErrors, "99")
+
+	requests = nil
+	assert.NoError(t, client.SendToUsers([]string{"1", "2"}, "test", "yolo"))
+	assert.Len(t, requests, 1)
+}
@@ -0,0 +1,32 @@
+package pusher
+
+import (
+	"encoding/json"
+)
+
+/*
+Codec encodes the data of events which is neither a string nor a byte slice,
+and decodes it back. It is set on `Client.Codec`, and is `JSONCodec` by default.
+
+The `pushercodec` package provides a faster JSON codec, `pushercodec.FastJSON`,
+as well as MessagePack and CBOR codecs.
+*/
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var _ Codec = JSONCodec{}
+
+// JSONCodec encodes data as JSON with encoding/json.
+type JSONCodec struct{}
+
+// Marshal implements Codec.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Codec.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
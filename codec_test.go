@@ -0,0 +1,90 @@
+package pusher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// base64Codec encodes data as JSON in base64, to tell its output apart from
+// that of JSONCodec.
+type base64Codec struct{}
+
+func (base64Codec) Marshal(v interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(encoded)), nil
+}
+
+func (base64Codec) Unmarshal(data []byte, v interface{}) error {
+	decoded, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(decoded, v)
+}
+
+func TestJSONCodecRoundTrip(t *testing.T) {
+	encoded, err := JSONCodec{}.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(encoded))
+
+	var decoded map[string]int
+	assert.NoError(t, JSONCodec{}.Unmarshal(encoded, &decoded))
+	assert.Equal(t, map[string]int{"a": 1}, decoded)
+}
+
+func TestTriggerWithCodec(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		raw, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(raw, &body)
+		res.WriteHeader(200)
+		res.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := &Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host, Codec: base64Codec{}}
+
+	err := client.Trigger("test_channel", "test", map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJhIjoxfQ==", body["data"])
+
+	err = client.Trigger("test_channel", "test", "raw string")
+	assert.NoError(t, err)
+	assert.Equal(t, "raw string", body["data"])
+
+	_, err = client.TriggerBatch([]Event{{Channel: "test_channel", Name: "test", Data: map[string]int{"a": 1}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJhIjoxfQ==", body["batch"].([]interface{})[0].(map[string]interface{})["data"])
+}
+
+func TestClientDecodeClientEvent(t *testing.T) {
+	client := &Client{Codec: base64Codec{}}
+
+	var decoded map[string]int
+	err := client.DecodeClientEvent(ClientEvent{Event: "client-test", Data: "eyJhIjoxfQ=="}, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, decoded)
+
+	var raw string
+	err = client.DecodeClientEvent(ClientEvent{Event: "client-test", Data: "eyJhIjoxfQ=="}, &raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJhIjoxfQ==", raw)
+
+	err = client.DecodeClientEvent(ClientEvent{Event: "client-test", DecryptionError: ErrDecryptionFailed}, &decoded)
+	assert.Equal(t, ErrDecryptionFailed, err)
+
+	err = (&Client{}).DecodeClientEvent(ClientEvent{Event: "client-test", Data: `{"a":2}`}, &decoded)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 2}, decoded)
+}
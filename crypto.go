@@ -9,8 +9,10 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/nacl/secretbox"
 )
@@ -21,6 +23,55 @@ type EncryptedMessage struct {
 	Ciphertext string `json:"ciphertext"`
 }
 
+var (
+	// ErrMalformedEncryptedMessage is returned when the data of an event of
+	// an encrypted channel is not an EncryptedMessage.
+	ErrMalformedEncryptedMessage = errors.New("Encrypted message is not valid JSON")
+	// ErrInvalidNonce is returned when the nonce of an encrypted message is
+	// not 24 bytes of valid base64.
+	ErrInvalidNonce = errors.New("Encrypted message has an invalid nonce")
+	// ErrInvalidCiphertext is returned when the ciphertext of an encrypted
+	// message is not valid base64, or is too short to have been encrypted.
+	ErrInvalidCiphertext = errors.New("Encrypted message has an invalid ciphertext")
+	// ErrDecryptionFailed is returned when none of the master keys decrypts
+	// an encrypted message.
+	ErrDecryptionFailed = errors.New("Failed to decrypt event, possibly wrong key?")
+)
+
+/*
+EncryptionMasterKey is an end-to-end encryption master key, identified by an
+`ID` of your choosing so that you can tell which key was used.
+
+Of the keys in `Client.EncryptionMasterKeys`, the one with the latest
+`ActiveFrom` which is not in the future is the primary key: it encrypts
+triggered events and generates the shared secrets handed out when authorizing
+channels. Every key is tried when decrypting events, so that clients holding a
+shared secret from a previous key keep working while the new key rolls out.
+*/
+type EncryptionMasterKey struct {
+	ID         string
+	KeyBase64  string    // 32 bytes, base64 encoded
+	ActiveFrom time.Time // when the key becomes primary, zero for always
+}
+
+// masterKey is a validated master key.
+type masterKey struct {
+	id         string
+	key        []byte
+	activeFrom time.Time
+}
+
+func (k EncryptionMasterKey) parse() (masterKey, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(k.KeyBase64)
+	if err != nil {
+		return masterKey{}, fmt.Errorf("Encryption master key '%s' must be valid base64", k.ID)
+	}
+	if len(keyBytes) != 32 {
+		return masterKey{}, fmt.Errorf("Encryption master key '%s' must encode 32 bytes", k.ID)
+	}
+	return masterKey{id: k.ID, key: keyBytes, activeFrom: k.ActiveFrom}, nil
+}
+
 func hmacSignature(toSign, secret string) string {
 	return hex.EncodeToString(hmacBytes([]byte(toSign), []byte(secret)))
 }
@@ -56,12 +107,17 @@ func md5Signature(body []byte) string {
 }
 
 func encrypt(channel string, data []byte, encryptionKey []byte) string {
+	encryptedMessage := encryptMessage(channel, data, encryptionKey)
+	return formatMessage(encryptedMessage.Nonce, encryptedMessage.Ciphertext)
+}
+
+func encryptMessage(channel string, data []byte, encryptionKey []byte) EncryptedMessage {
 	sharedSecret := generateSharedSecret(channel, encryptionKey)
 	nonce := generateNonce()
 	nonceB64 := base64.StdEncoding.EncodeToString(nonce[:])
 	cipherText := secretbox.Seal([]byte{}, data, &nonce, &sharedSecret)
 	cipherTextB64 := base64.StdEncoding.EncodeToString(cipherText)
-	return formatMessage(nonceB64, cipherTextB64)
+	return EncryptedMessage{Nonce: nonceB64, Ciphertext: cipherTextB64}
 }
 
 func formatMessage(nonce string, cipherText string) string {
@@ -90,34 +146,53 @@ func generateSharedSecret(channel string, encryptionKey []byte) [32]byte {
 	return sha256.Sum256(append([]byte(channel), encryptionKey...))
 }
 
-func decryptEvents(webhookData Webhook, encryptionKey []byte) (*Webhook, error) {
+// decryptMessage tries each of keys in turn, and returns the decrypted data
+// along with the ID of the key which decrypted it.
+func decryptMessage(channel string, encryptedMessage EncryptedMessage, keys []masterKey) ([]byte, string, error) {
+	nonceBytes, err := base64.StdEncoding.DecodeString(encryptedMessage.Nonce)
+	if err != nil || len(nonceBytes) != 24 {
+		return nil, "", ErrInvalidNonce
+	}
+	cipherTextBytes, err := base64.StdEncoding.DecodeString(encryptedMessage.Ciphertext)
+	if err != nil || len(cipherTextBytes) < secretbox.Overhead {
+		return nil, "", ErrInvalidCiphertext
+	}
+	// Convert slice to fixed length array for secretbox
+	var nonce [24]byte
+	copy(nonce[:], nonceBytes)
+
+	for _, key := range keys {
+		sharedSecret := generateSharedSecret(channel, key.key)
+		decryptedBox, ok := secretbox.Open([]byte{}, cipherTextBytes, &nonce, &sharedSecret)
+		if ok {
+			return decryptedBox, key.id, nil
+		}
+	}
+	return nil, "", ErrDecryptionFailed
+}
+
+/*
+decryptEvents decrypts the events of private-encrypted- channels. An event
+which cannot be decrypted keeps its encrypted data, and the reason is recorded
+in its DecryptionError, so that it does not prevent the other events from being
+delivered.
+*/
+func decryptEvents(webhookData Webhook, keys []masterKey) *Webhook {
 	decryptedWebhooks := &Webhook{}
 	decryptedWebhooks.TimeMs = webhookData.TimeMs
 	for _, event := range webhookData.Events {
 		if isEncryptedChannel(event.Channel) {
 			var encryptedMessage EncryptedMessage
-			json.Unmarshal([]byte(event.Data), &encryptedMessage)
-			cipherTextBytes, decodePayloadErr := base64.StdEncoding.DecodeString(encryptedMessage.Ciphertext)
-			if decodePayloadErr != nil {
-				return decryptedWebhooks, decodePayloadErr
-			}
-			nonceBytes, decodeNonceErr := base64.StdEncoding.DecodeString(encryptedMessage.Nonce)
-			if decodeNonceErr != nil {
-				return decryptedWebhooks, decodeNonceErr
-			}
-			// Convert slice to fixed length array for secretbox
-			var nonce [24]byte
-			copy(nonce[:], []byte(nonceBytes[:]))
-
-			sharedSecret := generateSharedSecret(event.Channel, encryptionKey)
-			box := []byte(cipherTextBytes)
-			decryptedBox, ok := secretbox.Open([]byte{}, box, &nonce, &sharedSecret)
-			if !ok {
-				return decryptedWebhooks, errors.New("Failed to decrypt event, possibly wrong key?")
+			if err := json.Unmarshal([]byte(event.Data), &encryptedMessage); err != nil {
+				event.DecryptionError = ErrMalformedEncryptedMessage
+			} else if decryptedBox, keyID, err := decryptMessage(event.Channel, encryptedMessage, keys); err != nil {
+				event.DecryptionError = err
+			} else {
+				event.Data = string(decryptedBox)
+				event.EncryptionMasterKeyID = keyID
 			}
-			event.Data = string(decryptedBox)
 		}
 		decryptedWebhooks.Events = append(decryptedWebhooks.Events, event)
 	}
-	return decryptedWebhooks, nil
+	return decryptedWebhooks
 }
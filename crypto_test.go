@@ -1,8 +1,13 @@
 package pusher
 
 import (
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"testing"
+	"time"
 
 	"gopkg.in/stretchr/testify.v1/assert"
 )
@@ -126,7 +131,7 @@ func TestDecryptValidKey(t *testing.T) {
 			},
 		},
 	}
-	decryptedWebhooks, _ := decryptEvents(*encryptedWebhookData, encryptionKey)
+	decryptedWebhooks := decryptEvents(*encryptedWebhookData, []masterKey{{key: encryptionKey}})
 	assert.Equal(t, expectedWebhookData, decryptedWebhooks)
 }
 
@@ -147,7 +152,136 @@ func TestDecryptInvalidKey(t *testing.T) {
 			},
 		},
 	}
-	decryptedWebhooks, err := decryptEvents(*encryptedWebhookData, encryptionKey)
-	assert.Equal(t, []WebhookEvent(nil), decryptedWebhooks.Events)
-	assert.EqualError(t, err, "Failed to decrypt event, possibly wrong key?")
+	decryptedWebhooks := decryptEvents(*encryptedWebhookData, []masterKey{{key: encryptionKey}})
+	assert.Equal(t, cipherText, decryptedWebhooks.Events[0].Data)
+	assert.EqualError(t, decryptedWebhooks.Events[0].DecryptionError, "Failed to decrypt event, possibly wrong key?")
+}
+
+func TestEncryptionMasterKeyring(t *testing.T) {
+	oldKey := []byte("This is the old key of 32 bytes!")
+	newKey := []byte("This is the new key of 32 bytes!")
+	futureKey := []byte("This is a future key of 32 bytes")
+	now := time.Now()
+	client := Client{
+		EncryptionMasterKeys: []EncryptionMasterKey{
+			{ID: "old", KeyBase64: base64.StdEncoding.EncodeToString(oldKey)},
+			{ID: "future", KeyBase64: base64.StdEncoding.EncodeToString(futureKey), ActiveFrom: now.Add(time.Hour)},
+			{ID: "new", KeyBase64: base64.StdEncoding.EncodeToString(newKey), ActiveFrom: now.Add(-time.Hour)},
+		},
+	}
+
+	primary, err := client.encryptionMasterKey()
+	assert.NoError(t, err)
+	assert.Equal(t, newKey, primary)
+
+	keys, err := client.encryptionMasterKeys()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"future", "new", "old"}, []string{keys[0].id, keys[1].id, keys[2].id})
+}
+
+func TestEncryptionMasterKeyringDecryptsWithOlderKeys(t *testing.T) {
+	oldKey := []byte("This is the old key of 32 bytes!")
+	newKey := []byte("This is the new key of 32 bytes!")
+	client := Client{
+		Key:                       "key",
+		Secret:                    "secret",
+		EncryptionMasterKeyBase64: base64.StdEncoding.EncodeToString(oldKey),
+		EncryptionMasterKeys: []EncryptionMasterKey{
+			{ID: "new", KeyBase64: base64.StdEncoding.EncodeToString(newKey), ActiveFrom: time.Now().Add(-time.Minute)},
+		},
+	}
+	data, _ := json.Marshal(encrypt("private-encrypted-a", []byte("Hello!"), oldKey))
+	body := fmt.Sprintf(`{"time_ms":1,"events":[{"name":"client_event","channel":"private-encrypted-a","data":%s}]}`, data)
+	header := make(http.Header)
+	header.Set("X-Pusher-Key", "key")
+	header.Set("X-Pusher-Signature", hmacSignature(body, "secret"))
+
+	webhook, err := client.Webhook(header, []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello!", webhook.Events[0].Data)
+	assert.Equal(t, "", webhook.Events[0].EncryptionMasterKeyID)
+
+	primary, _ := client.encryptionMasterKey()
+	assert.Equal(t, newKey, primary)
+}
+
+func TestEncryptionMasterKeyringErrors(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("This is the old key of 32 bytes!"))
+
+	client := Client{EncryptionMasterKeys: []EncryptionMasterKey{{ID: "a", KeyBase64: key}, {ID: "a", KeyBase64: key}}}
+	_, err := client.encryptionMasterKey()
+	assert.EqualError(t, err, "Encryption master key 'a' is specified more than once")
+
+	client = Client{EncryptionMasterKeys: []EncryptionMasterKey{{ID: "a", KeyBase64: key, ActiveFrom: time.Now().Add(time.Hour)}}}
+	_, err = client.encryptionMasterKey()
+	assert.EqualError(t, err, "None of the encryption master keys is active yet")
+
+	client = Client{}
+	_, err = client.encryptionMasterKey()
+	assert.EqualError(t, err, "No master encryption key supplied")
+}
+
+func TestEncryptAndDecryptForChannel(t *testing.T) {
+	client := Client{EncryptionMasterKeyBase64: "ZUhQVldIZzduRkdZVkJzS2pPRkRYV1JyaWJJUjJiMGI="}
+	message, err := client.EncryptForChannel("private-encrypted-a", map[string]string{"hello": "world"})
+	assert.NoError(t, err)
+
+	data, err := client.DecryptForChannel("private-encrypted-a", *message)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"hello":"world"}`, string(data))
+
+	_, err = client.DecryptForChannel("private-encrypted-b", *message)
+	assert.Equal(t, ErrDecryptionFailed, err)
+
+	_, err = client.EncryptForChannel("private-a", "hello")
+	assert.EqualError(t, err, "Channel 'private-a' is not an encrypted channel")
+}
+
+func TestDecryptForChannelMalformedMessages(t *testing.T) {
+	client := Client{EncryptionMasterKeyBase64: "ZUhQVldIZzduRkdZVkJzS2pPRkRYV1JyaWJJUjJiMGI="}
+
+	_, err := client.DecryptForChannel("private-encrypted-a", EncryptedMessage{Nonce: "c2hvcnQ=", Ciphertext: "zoDEe8dA3nDXKsybAWce/hXGW4szJw=="})
+	assert.Equal(t, ErrInvalidNonce, err)
+
+	_, err = client.DecryptForChannel("private-encrypted-a", EncryptedMessage{Nonce: "sjklahvpWWQgAjTx5FfYHCCxd2AmaL9T", Ciphertext: "!!"})
+	assert.Equal(t, ErrInvalidCiphertext, err)
+
+	_, err = client.DecryptForChannel("private-encrypted-a", EncryptedMessage{Nonce: "sjklahvpWWQgAjTx5FfYHCCxd2AmaL9T", Ciphertext: "c2hvcnQ="})
+	assert.Equal(t, ErrInvalidCiphertext, err)
+}
+
+func TestSharedSecretForChannel(t *testing.T) {
+	client := Client{EncryptionMasterKey: "This is a string that is 32 char"}
+	sharedSecret, err := client.SharedSecretForChannel("private-encrypted-bla")
+	assert.NoError(t, err)
+	expected := generateSharedSecret("private-encrypted-bla", []byte("This is a string that is 32 char"))
+	assert.Equal(t, expected[:], sharedSecret)
+
+	_, err = client.SharedSecretForChannel("presence-bla")
+	assert.Error(t, err)
+}
+
+func TestDecryptEventsReportsErrorsPerEvent(t *testing.T) {
+	encryptionKey := []byte("This is a string that is 32 chars")
+	valid := `{"nonce":"sjklahvpWWQgAjTx5FfYHCCxd2AmaL9T","ciphertext":"zoDEe8dA3nDXKsybAWce/hXGW4szJw=="}`
+	encryptedWebhookData := Webhook{
+		TimeMs: 1,
+		Events: []WebhookEvent{
+			{Name: "client_event", Channel: "private-encrypted-bla", Data: "not json"},
+			{Name: "client_event", Channel: "private-encrypted-bla", Data: `{"nonce":"c2hvcnQ=","ciphertext":"zoDEe8dA3nDXKsybAWce/hXGW4szJw=="}`},
+			{Name: "client_event", Channel: "private-encrypted-bla", Data: `{"nonce":"sjklahvpWWQgAjTx5FfYHCCxd2AmaL9T","ciphertext":"%%%"}`},
+			{Name: "client_event", Channel: "private-encrypted-bla", Data: valid},
+			{Name: "client_event", Channel: "private-bla", Data: "plain"},
+		},
+	}
+
+	decryptedWebhooks := decryptEvents(encryptedWebhookData, []masterKey{{key: encryptionKey}})
+	assert.Len(t, decryptedWebhooks.Events, 5)
+	assert.Equal(t, ErrMalformedEncryptedMessage, decryptedWebhooks.Events[0].DecryptionError)
+	assert.Equal(t, ErrInvalidNonce, decryptedWebhooks.Events[1].DecryptionError)
+	assert.Equal(t, ErrInvalidCiphertext, decryptedWebhooks.Events[2].DecryptionError)
+	assert.NoError(t, decryptedWebhooks.Events[3].DecryptionError)
+	assert.Equal(t, "Hello!", decryptedWebhooks.Events[3].Data)
+	assert.NoError(t, decryptedWebhooks.Events[4].DecryptionError)
+	assert.Equal(t, "plain", decryptedWebhooks.Events[4].Data)
 }
@@ -28,8 +28,9 @@ func encodeTriggerBody(
 	params map[string]string,
 	encryptionKey []byte,
 	overrideMaxMessagePayloadKB int,
+	codec Codec,
 ) ([]byte, error) {
-	dataBytes, err := encodeEventData(data)
+	dataBytes, err := encodeEventData(data, codec)
 	if err != nil {
 		return nil, err
 	}
@@ -67,11 +68,12 @@ func encodeTriggerBatchBody(
 	batch []Event,
 	encryptionKey []byte,
 	overrideMaxMessagePayloadKB int,
+	codec Codec,
 ) ([]byte, error) {
 	batchEvents := make([]batchEvent, len(batch))
 	for idx, e := range batch {
 		var stringifyedDataBytes string
-		dataBytes, err := encodeEventData(e.Data)
+		dataBytes, err := encodeEventData(e.Data, codec)
 		if err != nil {
 			return nil, err
 		}
@@ -101,20 +103,42 @@ func encodeTriggerBatchBody(
 	return json.Marshal(&batchPayload{batchEvents})
 }
 
-func encodeEventData(data interface{}) ([]byte, error) {
+// encodeEventData encodes data with codec, or as JSON if codec is nil. Strings
+// and byte slices are not encoded.
+func encodeEventData(data interface{}, codec Codec) ([]byte, error) {
 	var dataBytes []byte
 	var err error
 
+	if codec == nil {
+		codec = JSONCodec{}
+	}
 	switch d := data.(type) {
 	case []byte:
 		dataBytes = d
 	case string:
 		dataBytes = []byte(d)
 	default:
-		dataBytes, err = json.Marshal(data)
+		dataBytes, err = codec.Marshal(data)
 		if err != nil {
 			return nil, err
 		}
 	}
 	return dataBytes, nil
 }
+
+// decodeEventData decodes data encoded by encodeEventData into v. Strings and
+// byte slices are not decoded.
+func decodeEventData(data string, v interface{}, codec Codec) error {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	switch d := v.(type) {
+	case *string:
+		*d = data
+	case *[]byte:
+		*d = []byte(data)
+	default:
+		return codec.Unmarshal([]byte(data), v)
+	}
+	return nil
+}
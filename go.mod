@@ -1,10 +1,21 @@
 module github.com/pusher/pusher-http-go/v5
 
-go 1.14
+go 1.18
 
 require (
-	github.com/davecgh/go-spew v1.1.0 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/fxamacker/cbor/v2 v2.6.0
+	github.com/json-iterator/go v1.1.12
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	golang.org/x/crypto v0.0.0-20200709230013-948cd5f35899
 	gopkg.in/stretchr/testify.v1 v1.2.2
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+	github.com/x448/float16 v0.8.4 // indirect
+	golang.org/x/sys v0.0.0-20190412213103-97732733099d // indirect
+)
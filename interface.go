@@ -0,0 +1,37 @@
+package pusher
+
+import (
+	"net/http"
+)
+
+/*
+ClientInterface is implemented by `*Client`. Depend on it rather than on
+`*Client` to be able to replace the client in unit tests, for example with a
+`pushertest.Recorder`.
+
+	type Notifier struct {
+		Pusher pusher.ClientInterface
+	}
+*/
+type ClientInterface interface {
+	Trigger(channel string, eventName string, data interface{}) error
+	TriggerWithParams(channel string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error)
+	TriggerMulti(channels []string, eventName string, data interface{}) error
+	TriggerMultiWithParams(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error)
+	TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error)
+	SendToUser(userId string, eventName string, data interface{}) error
+	SendToUserWithParams(userId string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error)
+	SendToUsers(userIds []string, eventName string, data interface{}) error
+
+	Channels(params ChannelsParams) (*ChannelsList, error)
+	Channel(name string, params ChannelParams) (*Channel, error)
+	GetChannelUsers(name string) (*Users, error)
+
+	AuthenticateUser(params []byte, userData map[string]interface{}) (response []byte, err error)
+	AuthorizePrivateChannel(params []byte) (response []byte, err error)
+	AuthorizePresenceChannel(params []byte, member MemberData) (response []byte, err error)
+
+	Webhook(header http.Header, body []byte) (*Webhook, error)
+}
+
+var _ ClientInterface = (*Client)(nil)
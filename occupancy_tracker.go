@@ -0,0 +1,256 @@
+package pusher
+
+import (
+	"sort"
+	"sync"
+)
+
+/*
+OccupancyTracker keeps the set of occupied channels, that is channels with at
+least one subscriber. It is fed by the channel_occupied and channel_vacated
+events of verified webhooks, and reconciled against `Client.Channels` by
+`Reconcile`, which should be called on startup.
+
+`OnOccupied` and `OnVacated` are called for every channel whose occupancy
+changes, and hooks registered with `OnVacancy` for a single channel. A channel
+vacated while its occupancy was unknown, such as before the first `Reconcile`,
+is reported as vacated too. Callbacks are made without holding the tracker's
+lock, so they may call its methods.
+
+	tracker := &pusher.OccupancyTracker{Client: client}
+	if err := tracker.Reconcile(); err != nil {
+		...
+	}
+	stop := tracker.OnVacancy("prices-AAPL", producer.Stop)
+	handler := &pusher.WebhookHandler{Client: client, WebhookEventHandler: tracker.WebhookEventHandler()}
+*/
+type OccupancyTracker struct {
+	Client     ClientInterface
+	OnOccupied func(channel string)
+	OnVacated  func(channel string)
+
+	mutex         sync.Mutex
+	occupied      map[string]bool         // occupancy of the channels known from webhooks
+	reconciled    bool                    // whether channels missing from occupied are known to be vacant
+	changed       map[int]map[string]bool // channels changed by webhooks, per reconciliation in flight
+	nextReconcile int
+	hooks         map[string]map[int]func()
+	nextHook      int
+}
+
+func (t *OccupancyTracker) init() {
+	if t.occupied == nil {
+		t.occupied = make(map[string]bool)
+		t.changed = make(map[int]map[string]bool)
+		t.hooks = make(map[string]map[int]func())
+	}
+}
+
+/*
+OnVacancy registers a hook to be called whenever channel is vacated, until the
+returned function is called to remove it.
+*/
+func (t *OccupancyTracker) OnVacancy(channel string, hook func()) (remove func()) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.init()
+	if t.hooks[channel] == nil {
+		t.hooks[channel] = make(map[int]func())
+	}
+	id := t.nextHook
+	t.nextHook++
+	t.hooks[channel][id] = hook
+
+	return func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		delete(t.hooks[channel], id)
+		if len(t.hooks[channel]) == 0 {
+			delete(t.hooks, channel)
+		}
+	}
+}
+
+// Occupy records a channel as occupied.
+func (t *OccupancyTracker) Occupy(channel string) {
+	t.set(channel, true)
+}
+
+// Vacate records a channel as vacated.
+func (t *OccupancyTracker) Vacate(channel string) {
+	t.set(channel, false)
+}
+
+func (t *OccupancyTracker) set(channel string, occupied bool) {
+	t.mutex.Lock()
+	t.init()
+	wasOccupied, known := t.occupied[channel]
+	known = known || t.reconciled
+	if occupied || !t.reconciled {
+		t.occupied[channel] = occupied
+	} else {
+		delete(t.occupied, channel)
+	}
+	for _, changed := range t.changed {
+		changed[channel] = true
+	}
+	vacated := !occupied && (wasOccupied || !known)
+	var hooks []func()
+	if vacated {
+		hooks = t.vacancyHooks(channel)
+	}
+	t.mutex.Unlock()
+
+	if occupied && !wasOccupied {
+		t.notifyOccupied(channel)
+	}
+	if vacated {
+		t.notifyVacated(channel, hooks)
+	}
+}
+
+func (t *OccupancyTracker) vacancyHooks(channel string) []func() {
+	ids := make([]int, 0, len(t.hooks[channel]))
+	for id := range t.hooks[channel] {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	hooks := make([]func(), len(ids))
+	for i, id := range ids {
+		hooks[i] = t.hooks[channel][id]
+	}
+	return hooks
+}
+
+func (t *OccupancyTracker) notifyOccupied(channel string) {
+	if t.OnOccupied != nil {
+		t.OnOccupied(channel)
+	}
+}
+
+func (t *OccupancyTracker) notifyVacated(channel string, hooks []func()) {
+	if t.OnVacated != nil {
+		t.OnVacated(channel)
+	}
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+/*
+WebhookEventHandler returns the callbacks which apply webhook events to the
+tracker, for use with `Webhook.Dispatch` or a `WebhookHandler`.
+*/
+func (t *OccupancyTracker) WebhookEventHandler() WebhookEventHandler {
+	return WebhookEventHandler{
+		OnChannelOccupied: func(e ChannelOccupiedEvent) error {
+			t.Occupy(e.Channel)
+			return nil
+		},
+		OnChannelVacated: func(e ChannelVacatedEvent) error {
+			t.Vacate(e.Channel)
+			return nil
+		},
+	}
+}
+
+// HandleWebhook applies the events of a verified webhook to the tracker.
+func (t *OccupancyTracker) HandleWebhook(webhook *Webhook) {
+	webhook.Dispatch(t.WebhookEventHandler())
+}
+
+/*
+Reconcile replaces the set of occupied channels with those returned by
+`Client.Channels`, calling the callbacks for every channel whose occupancy
+changed. Channels changed by a webhook while the request is in flight keep the
+occupancy given by the webhook, since the response may predate it.
+*/
+func (t *OccupancyTracker) Reconcile() error {
+	t.mutex.Lock()
+	t.init()
+	id := t.nextReconcile
+	t.nextReconcile++
+	changed := make(map[string]bool)
+	t.changed[id] = changed
+	t.mutex.Unlock()
+
+	channels, err := t.Client.Channels(ChannelsParams{})
+
+	t.mutex.Lock()
+	delete(t.changed, id)
+	if err != nil {
+		t.mutex.Unlock()
+		return err
+	}
+	var occupied, vacated []string
+	for channel := range channels.Channels {
+		if !changed[channel] && !t.occupied[channel] {
+			occupied = append(occupied, channel)
+		}
+	}
+	for channel, wasOccupied := range t.occupied {
+		if _, ok := channels.Channels[channel]; wasOccupied && !ok && !changed[channel] {
+			vacated = append(vacated, channel)
+		}
+	}
+	sort.Strings(occupied)
+	sort.Strings(vacated)
+	hooks := make([][]func(), len(vacated))
+	for i, channel := range vacated {
+		hooks[i] = t.vacancyHooks(channel)
+	}
+	reconciled := make(map[string]bool, len(channels.Channels))
+	for channel := range channels.Channels {
+		if !changed[channel] {
+			reconciled[channel] = true
+		}
+	}
+	for channel := range changed {
+		if t.occupied[channel] {
+			reconciled[channel] = true
+		}
+	}
+	t.occupied = reconciled
+	t.reconciled = true
+	t.mutex.Unlock()
+
+	for _, channel := range occupied {
+		t.notifyOccupied(channel)
+	}
+	for i, channel := range vacated {
+		t.notifyVacated(channel, hooks[i])
+	}
+	return nil
+}
+
+// IsOccupied reports whether a channel is known to be occupied.
+func (t *OccupancyTracker) IsOccupied(channel string) bool {
+	occupied, _ := t.Occupancy(channel)
+	return occupied
+}
+
+/*
+Occupancy reports whether a channel is occupied, and whether that is known.
+The occupancy of a channel is unknown until a webhook about it is received or
+the tracker is reconciled.
+*/
+func (t *OccupancyTracker) Occupancy(channel string) (occupied, known bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	occupied, known = t.occupied[channel]
+	return occupied, known || t.reconciled
+}
+
+// Channels returns the occupied channels, sorted.
+func (t *OccupancyTracker) Channels() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	channels := make([]string, 0, len(t.occupied))
+	for channel, occupied := range t.occupied {
+		if occupied {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
@@ -0,0 +1,161 @@
+package pusher
+
+import (
+	"errors"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type channelsClient struct {
+	*Client
+	channels []string
+	err      error
+	during   func()
+}
+
+func (c *channelsClient) Channels(params ChannelsParams) (*ChannelsList, error) {
+	if c.during != nil {
+		c.during()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	list := &ChannelsList{Channels: map[string]ChannelListItem{}}
+	for _, channel := range c.channels {
+		list.Channels[channel] = ChannelListItem{}
+	}
+	return list, nil
+}
+
+func TestOccupancyTrackerAppliesWebhooks(t *testing.T) {
+	var occupied, vacated []string
+	tracker := &OccupancyTracker{
+		OnOccupied: func(channel string) { occupied = append(occupied, channel) },
+		OnVacated:  func(channel string) { vacated = append(vacated, channel) },
+	}
+
+	occupancy, known := tracker.Occupancy("a")
+	assert.False(t, occupancy)
+	assert.False(t, known)
+
+	tracker.HandleWebhook(&Webhook{Events: []WebhookEvent{
+		{Name: WebhookChannelOccupied, Channel: "a"},
+		{Name: WebhookChannelOccupied, Channel: "b"},
+		{Name: WebhookChannelOccupied, Channel: "b"},
+		{Name: WebhookChannelVacated, Channel: "a"},
+	}})
+	assert.Equal(t, []string{"b"}, tracker.Channels())
+	assert.Equal(t, []string{"a", "b"}, occupied)
+	assert.Equal(t, []string{"a"}, vacated)
+
+	occupancy, known = tracker.Occupancy("a")
+	assert.False(t, occupancy)
+	assert.True(t, known)
+	assert.True(t, tracker.IsOccupied("b"))
+}
+
+func TestOccupancyTrackerVacancyHooks(t *testing.T) {
+	tracker := &OccupancyTracker{}
+	calls := 0
+	remove := tracker.OnVacancy("a", func() { calls++ })
+	tracker.OnVacancy("b", func() { t.Error("hook of another channel called") })
+
+	tracker.Vacate("a")
+	tracker.Vacate("a")
+	assert.Equal(t, 1, calls)
+
+	tracker.Occupy("a")
+	tracker.Vacate("a")
+	tracker.Occupy("a")
+	tracker.Vacate("a")
+	assert.Equal(t, 3, calls)
+
+	remove()
+	tracker.Occupy("a")
+	tracker.Vacate("a")
+	assert.Equal(t, 3, calls)
+}
+
+func TestOccupancyTrackerReportsVacancyOfUnknownChannels(t *testing.T) {
+	client := &channelsClient{}
+	var vacated []string
+	tracker := &OccupancyTracker{Client: client, OnVacated: func(channel string) { vacated = append(vacated, channel) }}
+
+	tracker.Vacate("a")
+	assert.Equal(t, []string{"a"}, vacated)
+
+	assert.NoError(t, tracker.Reconcile())
+	tracker.Vacate("b")
+	assert.Equal(t, []string{"a"}, vacated)
+}
+
+func TestOccupancyTrackerReconcile(t *testing.T) {
+	client := &channelsClient{channels: []string{"b", "c"}}
+	var occupied, vacated []string
+	tracker := &OccupancyTracker{
+		Client:     client,
+		OnOccupied: func(channel string) { occupied = append(occupied, channel) },
+		OnVacated:  func(channel string) { vacated = append(vacated, channel) },
+	}
+	tracker.Occupy("a")
+	tracker.Occupy("b")
+	hooked := false
+	tracker.OnVacancy("a", func() { hooked = true })
+	occupied = nil
+
+	assert.NoError(t, tracker.Reconcile())
+	assert.Equal(t, []string{"b", "c"}, tracker.Channels())
+	assert.Equal(t, []string{"c"}, occupied)
+	assert.Equal(t, []string{"a"}, vacated)
+	assert.True(t, hooked)
+
+	occupancy, known := tracker.Occupancy("never-seen")
+	assert.False(t, occupancy)
+	assert.True(t, known)
+}
+
+func TestOccupancyTrackerReconcileKeepsChannelsChangedInFlight(t *testing.T) {
+	client := &channelsClient{channels: []string{"b", "c"}}
+	var occupied, vacated []string
+	tracker := &OccupancyTracker{
+		Client:     client,
+		OnOccupied: func(channel string) { occupied = append(occupied, channel) },
+		OnVacated:  func(channel string) { vacated = append(vacated, channel) },
+	}
+	tracker.Occupy("d")
+	client.during = func() {
+		tracker.Occupy("a")
+		tracker.Vacate("b")
+		tracker.Occupy("d")
+	}
+
+	assert.NoError(t, tracker.Reconcile())
+	assert.Equal(t, []string{"a", "c", "d"}, tracker.Channels())
+	assert.Equal(t, []string{"d", "a", "c"}, occupied)
+	assert.Equal(t, []string{"b"}, vacated)
+	_, known := tracker.Occupancy("e")
+	assert.True(t, known)
+}
+
+func TestOccupancyTrackerForgetsVacatedChannels(t *testing.T) {
+	tracker := &OccupancyTracker{Client: &channelsClient{}}
+	tracker.Vacate("a")
+	tracker.Occupy("b")
+	assert.Len(t, tracker.occupied, 2)
+
+	assert.NoError(t, tracker.Reconcile())
+	assert.Len(t, tracker.occupied, 0)
+
+	tracker.Occupy("c")
+	tracker.Vacate("c")
+	assert.Len(t, tracker.occupied, 0)
+	occupancy, known := tracker.Occupancy("c")
+	assert.False(t, occupancy)
+	assert.True(t, known)
+}
+
+func TestOccupancyTrackerReconcileError(t *testing.T) {
+	tracker := &OccupancyTracker{Client: &channelsClient{err: errors.New("unavailable")}}
+	assert.EqualError(t, tracker.Reconcile(), "unavailable")
+}
@@ -0,0 +1,326 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrReconcileRaced is returned when a webhook was applied while the request of
+// a reconciliation was in flight. The response was discarded, since it may
+// predate the webhook, and the reconciliation should be retried.
+var ErrReconcileRaced = errors.New("Reconciliation raced a webhook and was discarded")
+
+// PresenceChange is the difference made to the roster of a presence-channel.
+type PresenceChange struct {
+	Channel string
+	Joined  []string // user IDs, sorted
+	Left    []string // user IDs, sorted
+}
+
+// PresenceSnapshot maps each tracked presence-channel to the sorted IDs of its
+// users. It can be marshalled to JSON to persist a PresenceTracker.
+type PresenceSnapshot map[string][]string
+
+/*
+PresenceTracker keeps an in-memory roster of the users of presence-channels. It
+is fed by the member_added, member_removed and channel_vacated events of verified
+webhooks, and periodically reconciled against `GetChannelUsers`, which is the
+source of truth if a webhook was missed.
+
+Every change to a roster is passed to `OnChange`. Callbacks are made without
+holding the tracker's lock, so they may call its methods.
+
+A presence-channel is tracked from the first webhook about it until it is
+vacated, or until a reconciliation finds it empty. Channels passed to `Track`
+stay tracked until they are passed to `Untrack`.
+
+	tracker := &pusher.PresenceTracker{
+		Client: client,
+		OnChange: func(change pusher.PresenceChange) {
+			log.Printf("%s: %v joined, %v left", change.Channel, change.Joined, change.Left)
+		},
+	}
+	handler := &pusher.WebhookHandler{Client: client, WebhookEventHandler: tracker.WebhookEventHandler()}
+	go tracker.Run(ctx, time.Minute)
+*/
+type PresenceTracker struct {
+	Client   ClientInterface
+	OnChange func(PresenceChange)
+	OnError  func(channel string, err error) // errors of reconciliations made by Run
+
+	mutex   sync.Mutex
+	rosters map[string]*presenceRoster
+}
+
+type presenceRoster struct {
+	users   map[string]struct{}
+	version uint64 // incremented by every change made by a webhook
+	tracked bool   // kept when empty, until Untrack
+}
+
+func (t *PresenceTracker) roster(channel string) *presenceRoster {
+	if t.rosters == nil {
+		t.rosters = make(map[string]*presenceRoster)
+	}
+	roster, ok := t.rosters[channel]
+	if !ok {
+		roster = &presenceRoster{users: make(map[string]struct{})}
+		t.rosters[channel] = roster
+	}
+	return roster
+}
+
+func (t *PresenceTracker) notify(change PresenceChange) {
+	if t.OnChange != nil && (len(change.Joined) > 0 || len(change.Left) > 0) {
+		t.OnChange(change)
+	}
+}
+
+/*
+Track starts tracking a presence-channel, so that it is reconciled by
+`ReconcileAll` before any webhook about it has been received, and while it is
+empty.
+*/
+func (t *PresenceTracker) Track(channel string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.roster(channel).tracked = true
+}
+
+/*
+Untrack stops tracking a presence-channel and forgets its roster, without
+calling `OnChange`. A later webhook about the channel tracks it again.
+*/
+func (t *PresenceTracker) Untrack(channel string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.rosters, channel)
+}
+
+// forgetIfEmpty forgets the roster of a channel which has no users, unless
+// the channel was passed to Track.
+func (t *PresenceTracker) forgetIfEmpty(channel string, roster *presenceRoster) {
+	if len(roster.users) == 0 && !roster.tracked {
+		delete(t.rosters, channel)
+	}
+}
+
+// Join adds a user to the roster of a presence-channel.
+func (t *PresenceTracker) Join(channel, userID string) {
+	t.mutex.Lock()
+	roster := t.roster(channel)
+	_, present := roster.users[userID]
+	roster.users[userID] = struct{}{}
+	roster.version++
+	t.mutex.Unlock()
+
+	if !present {
+		t.notify(PresenceChange{Channel: channel, Joined: []string{userID}})
+	}
+}
+
+// Leave removes a user from the roster of a presence-channel.
+func (t *PresenceTracker) Leave(channel, userID string) {
+	t.mutex.Lock()
+	roster := t.roster(channel)
+	_, present := roster.users[userID]
+	delete(roster.users, userID)
+	roster.version++
+	t.mutex.Unlock()
+
+	if present {
+		t.notify(PresenceChange{Channel: channel, Left: []string{userID}})
+	}
+}
+
+// vacate removes every user from the roster of a presence-channel.
+func (t *PresenceTracker) vacate(channel string) {
+	t.mutex.Lock()
+	roster := t.roster(channel)
+	left := sortedUserIDs(roster.users)
+	roster.users = make(map[string]struct{})
+	roster.version++
+	t.forgetIfEmpty(channel, roster)
+	t.mutex.Unlock()
+
+	t.notify(PresenceChange{Channel: channel, Left: left})
+}
+
+/*
+WebhookEventHandler returns the callbacks which apply webhook events to the
+rosters, for use with `Webhook.Dispatch` or a `WebhookHandler`.
+*/
+func (t *PresenceTracker) WebhookEventHandler() WebhookEventHandler {
+	return WebhookEventHandler{
+		OnMemberAdded: func(e MemberAddedEvent) error {
+			t.Join(e.Channel, e.UserID)
+			return nil
+		},
+		OnMemberRemoved: func(e MemberRemovedEvent) error {
+			t.Leave(e.Channel, e.UserID)
+			return nil
+		},
+		OnChannelVacated: func(e ChannelVacatedEvent) error {
+			if ChannelName(e.Channel).Kind() == PresenceChannelKind {
+				t.vacate(e.Channel)
+			}
+			return nil
+		},
+	}
+}
+
+// HandleWebhook applies the events of a verified webhook to the rosters.
+func (t *PresenceTracker) HandleWebhook(webhook *Webhook) {
+	webhook.Dispatch(t.WebhookEventHandler())
+}
+
+/*
+Reconcile replaces the roster of a presence-channel with the users returned by
+`GetChannelUsers`. If a webhook changes the roster while the request is in
+flight, the response is discarded, since it may predate the webhook, and
+`ErrReconcileRaced` is returned.
+*/
+func (t *PresenceTracker) Reconcile(channel string) error {
+	t.mutex.Lock()
+	roster := t.roster(channel)
+	version := roster.version
+	t.mutex.Unlock()
+
+	users, err := t.Client.GetChannelUsers(channel)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]struct{}, len(users.List))
+	for _, user := range users.List {
+		current[user.ID] = struct{}{}
+	}
+
+	t.mutex.Lock()
+	if t.rosters[channel] != roster || roster.version != version {
+		t.mutex.Unlock()
+		return ErrReconcileRaced
+	}
+	change := diffRosters(channel, roster.users, current)
+	roster.users = current
+	t.forgetIfEmpty(channel, roster)
+	t.mutex.Unlock()
+
+	t.notify(change)
+	return nil
+}
+
+/*
+ReconcileAll reconciles every tracked presence-channel, returning the first
+error after attempting all of them.
+*/
+func (t *PresenceTracker) ReconcileAll() error {
+	var firstErr error
+	for _, channel := range t.Channels() {
+		if err := t.Reconcile(channel); err != nil {
+			if t.OnError != nil {
+				t.OnError(channel, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+/*
+Run calls `ReconcileAll` every interval until ctx is done, then returns the
+context's error. Errors are reported through `OnError`.
+*/
+func (t *PresenceTracker) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			t.ReconcileAll()
+		}
+	}
+}
+
+// Channels returns the tracked presence-channels, sorted.
+func (t *PresenceTracker) Channels() []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	channels := make([]string, 0, len(t.rosters))
+	for channel := range t.rosters {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// Users returns the IDs of the users of a presence-channel, sorted.
+func (t *PresenceTracker) Users(channel string) []string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	roster, ok := t.rosters[channel]
+	if !ok {
+		return []string{}
+	}
+	return sortedUserIDs(roster.users)
+}
+
+// Snapshot returns the rosters of every tracked presence-channel.
+func (t *PresenceTracker) Snapshot() PresenceSnapshot {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	snapshot := make(PresenceSnapshot, len(t.rosters))
+	for channel, roster := range t.rosters {
+		snapshot[channel] = sortedUserIDs(roster.users)
+	}
+	return snapshot
+}
+
+/*
+Restore replaces the rosters with those of a snapshot, such as one persisted
+before a restart, without calling `OnChange`. Call `ReconcileAll` afterwards to
+catch up on the changes made since the snapshot was taken.
+*/
+func (t *PresenceTracker) Restore(snapshot PresenceSnapshot) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.rosters = make(map[string]*presenceRoster, len(snapshot))
+	for channel, userIDs := range snapshot {
+		roster := t.roster(channel)
+		for _, userID := range userIDs {
+			roster.users[userID] = struct{}{}
+		}
+	}
+}
+
+func diffRosters(channel string, previous, current map[string]struct{}) PresenceChange {
+	change := PresenceChange{Channel: channel}
+	for userID := range current {
+		if _, ok := previous[userID]; !ok {
+			change.Joined = append(change.Joined, userID)
+		}
+	}
+	for userID := range previous {
+		if _, ok := current[userID]; !ok {
+			change.Left = append(change.Left, userID)
+		}
+	}
+	sort.Strings(change.Joined)
+	sort.Strings(change.Left)
+	return change
+}
+
+func sortedUserIDs(users map[string]struct{}) []string {
+	userIDs := make([]string, 0, len(users))
+	for userID := range users {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	return userIDs
+}
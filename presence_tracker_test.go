@@ -0,0 +1,153 @@
+package pusher
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type rosterClient struct {
+	*Client
+	users  map[string][]string
+	err    error
+	during func()
+}
+
+func (c *rosterClient) GetChannelUsers(name string) (*Users, error) {
+	if c.during != nil {
+		c.during()
+	}
+	if c.err != nil {
+		return nil, c.err
+	}
+	users := &Users{List: []User{}}
+	for _, id := range c.users[name] {
+		users.List = append(users.List, User{ID: id})
+	}
+	return users, nil
+}
+
+func recordChanges(tracker *PresenceTracker) *[]PresenceChange {
+	changes := &[]PresenceChange{}
+	tracker.OnChange = func(change PresenceChange) {
+		*changes = append(*changes, change)
+	}
+	return changes
+}
+
+func TestPresenceTrackerAppliesWebhooks(t *testing.T) {
+	tracker := &PresenceTracker{}
+	changes := recordChanges(tracker)
+
+	tracker.HandleWebhook(&Webhook{Events: []WebhookEvent{
+		{Name: WebhookMemberAdded, Channel: "presence-room", UserID: "1"},
+		{Name: WebhookMemberAdded, Channel: "presence-room", UserID: "2"},
+		{Name: WebhookMemberAdded, Channel: "presence-room", UserID: "2"},
+		{Name: WebhookMemberRemoved, Channel: "presence-room", UserID: "1"},
+		{Name: WebhookChannelOccupied, Channel: "presence-room"},
+	}})
+	assert.Equal(t, []string{"2"}, tracker.Users("presence-room"))
+	assert.Equal(t, []PresenceChange{
+		{Channel: "presence-room", Joined: []string{"1"}},
+		{Channel: "presence-room", Joined: []string{"2"}},
+		{Channel: "presence-room", Left: []string{"1"}},
+	}, *changes)
+
+	tracker.HandleWebhook(&Webhook{Events: []WebhookEvent{{Name: WebhookChannelVacated, Channel: "presence-room"}}})
+	assert.Empty(t, tracker.Users("presence-room"))
+	assert.Equal(t, PresenceChange{Channel: "presence-room", Left: []string{"2"}}, (*changes)[3])
+}
+
+func TestPresenceTrackerReconcile(t *testing.T) {
+	client := &rosterClient{users: map[string][]string{"presence-room": {"2", "3"}}}
+	tracker := &PresenceTracker{Client: client}
+	tracker.Join("presence-room", "1")
+	tracker.Join("presence-room", "2")
+	changes := recordChanges(tracker)
+
+	assert.NoError(t, tracker.ReconcileAll())
+	assert.Equal(t, []string{"2", "3"}, tracker.Users("presence-room"))
+	assert.Equal(t, []PresenceChange{{Channel: "presence-room", Joined: []string{"3"}, Left: []string{"1"}}}, *changes)
+
+	assert.NoError(t, tracker.Reconcile("presence-room"))
+	assert.Len(t, *changes, 1)
+}
+
+func TestPresenceTrackerReconcileDiscardsResponsesRacingWebhooks(t *testing.T) {
+	client := &rosterClient{users: map[string][]string{"presence-room": {}}}
+	tracker := &PresenceTracker{Client: client}
+	client.during = func() { tracker.Join("presence-room", "1") }
+
+	assert.Equal(t, ErrReconcileRaced, tracker.Reconcile("presence-room"))
+	assert.Equal(t, []string{"1"}, tracker.Users("presence-room"))
+}
+
+func TestPresenceTrackerForgetsEmptyChannels(t *testing.T) {
+	client := &rosterClient{users: map[string][]string{}}
+	tracker := &PresenceTracker{Client: client}
+	tracker.Join("presence-a", "1")
+	tracker.Join("presence-b", "1")
+	tracker.Track("presence-c")
+
+	tracker.HandleWebhook(&Webhook{Events: []WebhookEvent{{Name: WebhookChannelVacated, Channel: "presence-a"}}})
+	assert.Equal(t, []string{"presence-b", "presence-c"}, tracker.Channels())
+
+	assert.NoError(t, tracker.ReconcileAll())
+	assert.Equal(t, []string{"presence-c"}, tracker.Channels())
+
+	tracker.Untrack("presence-c")
+	assert.Empty(t, tracker.Channels())
+}
+
+func TestPresenceTrackerReconcileDiscardsResponsesRacingVacancy(t *testing.T) {
+	client := &rosterClient{users: map[string][]string{"presence-room": {"1"}}}
+	tracker := &PresenceTracker{Client: client}
+	client.during = func() { tracker.vacate("presence-room") }
+
+	assert.Equal(t, ErrReconcileRaced, tracker.Reconcile("presence-room"))
+	assert.Empty(t, tracker.Channels())
+}
+
+func TestPresenceTrackerReconcileErrors(t *testing.T) {
+	client := &rosterClient{err: errors.New("unavailable")}
+	var failed []string
+	tracker := &PresenceTracker{Client: client, OnError: func(channel string, err error) {
+		failed = append(failed, channel)
+	}}
+	tracker.Track("presence-a")
+	tracker.Track("presence-b")
+
+	assert.EqualError(t, tracker.ReconcileAll(), "unavailable")
+	assert.Equal(t, []string{"presence-a", "presence-b"}, failed)
+}
+
+func TestPresenceTrackerSnapshotAndRestore(t *testing.T) {
+	tracker := &PresenceTracker{}
+	tracker.Join("presence-a", "2")
+	tracker.Join("presence-a", "1")
+	tracker.Track("presence-b")
+	snapshot := tracker.Snapshot()
+	assert.Equal(t, PresenceSnapshot{"presence-a": {"1", "2"}, "presence-b": {}}, snapshot)
+
+	restored := &PresenceTracker{}
+	changes := recordChanges(restored)
+	restored.Restore(snapshot)
+	assert.Equal(t, []string{"presence-a", "presence-b"}, restored.Channels())
+	assert.Equal(t, []string{"1", "2"}, restored.Users("presence-a"))
+	assert.Empty(t, *changes)
+}
+
+func TestPresenceTrackerRunStopsWithContext(t *testing.T) {
+	client := &rosterClient{users: map[string][]string{"presence-room": {"1"}}}
+	tracker := &PresenceTracker{Client: client}
+	tracker.Track("presence-room")
+	ctx, cancel := context.WithCancel(context.Background())
+	tracker.OnChange = func(PresenceChange) { cancel() }
+
+	err := tracker.Run(ctx, time.Millisecond)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, []string{"1"}, tracker.Users("presence-room"))
+}
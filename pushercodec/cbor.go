@@ -0,0 +1,29 @@
+package pushercodec
+
+import (
+	"github.com/fxamacker/cbor/v2"
+)
+
+// cborEncoding sorts the keys of maps, so that equal values are encoded alike.
+var cborEncoding, _ = cbor.CanonicalEncOptions().EncMode()
+
+// CBOR encodes data as CBOR, in base64.
+type CBOR struct{}
+
+// Marshal implements pusher.Codec.
+func (CBOR) Marshal(v interface{}) ([]byte, error) {
+	encoded, err := cborEncoding.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return encodeBase64(encoded), nil
+}
+
+// Unmarshal implements pusher.Codec.
+func (CBOR) Unmarshal(data []byte, v interface{}) error {
+	decoded, err := decodeBase64(data)
+	if err != nil {
+		return err
+	}
+	return cbor.Unmarshal(decoded, v)
+}
@@ -0,0 +1,45 @@
+package pushercodec
+
+import (
+	"testing"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+var _ pusher.Codec = CBOR{}
+
+func TestCBOREncoding(t *testing.T) {
+	encoded, err := CBOR{}.Marshal(map[string]int{"b": 2, "a": 1})
+	assert.NoError(t, err)
+	// a2 61 61 01 61 62 02
+	assert.Equal(t, "omFhAWFiAg==", string(encoded))
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	encoded, err := CBOR{}.Marshal(testMessage)
+	assert.NoError(t, err)
+
+	var decoded message
+	assert.NoError(t, CBOR{}.Unmarshal(encoded, &decoded))
+	assert.Equal(t, testMessage, decoded)
+
+	var fields map[string]interface{}
+	assert.NoError(t, CBOR{}.Unmarshal(encoded, &fields))
+	assert.Contains(t, fields, "text")
+}
+
+func TestCBORMalformedData(t *testing.T) {
+	var v interface{}
+	assert.Error(t, CBOR{}.Unmarshal([]byte("not base64!"), &v))
+	// A map announcing more entries than it has.
+	assert.Error(t, CBOR{}.Unmarshal([]byte("ow=="), &v))
+}
+
+func TestCBORWithClient(t *testing.T) {
+	client := &pusher.Client{Codec: CBOR{}}
+
+	var decoded map[string]int
+	assert.NoError(t, client.DecodeClientEvent(pusher.ClientEvent{Event: "client-a", Data: "oWFhAQ=="}, &decoded))
+	assert.Equal(t, map[string]int{"a": 1}, decoded)
+}
@@ -0,0 +1,15 @@
+/*
+Package pushercodec provides codecs for the data of events, for use as
+`pusher.Client.Codec`. FastJSON encodes the same JSON as the default codec,
+only faster. Msgpack and CBOR encode data in compact binary formats. Since the
+data of events is a string, the binary encoding is carried in base64, which
+clients decode before the binary format.
+
+	client.Codec = pushercodec.Msgpack{}
+	client.Trigger("my-channel", "my-event", map[string]int{"a": 1})
+	// data: "gaFhAQ=="
+
+Struct fields are named by their `json` tags, as they are by the default JSON
+codec, so the same types can be used with every codec.
+*/
+package pushercodec
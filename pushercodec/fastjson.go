@@ -0,0 +1,22 @@
+package pushercodec
+
+import (
+	jsoniter "github.com/json-iterator/go"
+)
+
+/*
+FastJSON encodes data as JSON with jsoniter, which is faster than
+encoding/json. Its output is the same as that of `pusher.JSONCodec`, so
+clients decode it as they would by default.
+*/
+type FastJSON struct{}
+
+// Marshal implements pusher.Codec.
+func (FastJSON) Marshal(v interface{}) ([]byte, error) {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Marshal(v)
+}
+
+// Unmarshal implements pusher.Codec.
+func (FastJSON) Unmarshal(data []byte, v interface{}) error {
+	return jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal(data, v)
+}
@@ -0,0 +1,38 @@
+package pushercodec
+
+import (
+	"testing"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"github.com/pusher/pusher-http-go/v5/pushertest"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+var _ pusher.Codec = FastJSON{}
+
+func TestFastJSONMatchesJSONCodec(t *testing.T) {
+	encoded, err := FastJSON{}.Marshal(testMessage)
+	assert.NoError(t, err)
+	expected, err := pusher.JSONCodec{}.Marshal(testMessage)
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), string(encoded))
+
+	var decoded message
+	assert.NoError(t, FastJSON{}.Unmarshal(encoded, &decoded))
+	assert.Equal(t, testMessage, decoded)
+}
+
+func TestFastJSONMalformedData(t *testing.T) {
+	var v interface{}
+	assert.Error(t, FastJSON{}.Unmarshal([]byte(`{"a":`), &v))
+}
+
+func TestFastJSONWithClient(t *testing.T) {
+	server := pushertest.NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+	client.Codec = FastJSON{}
+
+	assert.NoError(t, client.Trigger("my-channel", "my-event", map[string]int{"a": 1}))
+	assert.Equal(t, `{"a":1}`, server.Events()[0].Data)
+}
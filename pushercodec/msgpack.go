@@ -0,0 +1,45 @@
+package pushercodec
+
+import (
+	"bytes"
+	"encoding/base64"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Msgpack encodes data as MessagePack, in base64.
+type Msgpack struct{}
+
+// Marshal implements pusher.Codec.
+func (Msgpack) Marshal(v interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := msgpack.NewEncoder(&buffer)
+	encoder.SetCustomStructTag("json")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return encodeBase64(buffer.Bytes()), nil
+}
+
+// Unmarshal implements pusher.Codec.
+func (Msgpack) Unmarshal(data []byte, v interface{}) error {
+	decoded, err := decodeBase64(data)
+	if err != nil {
+		return err
+	}
+	decoder := msgpack.NewDecoder(bytes.NewReader(decoded))
+	decoder.SetCustomStructTag("json")
+	return decoder.Decode(v)
+}
+
+func encodeBase64(data []byte) []byte {
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
+	base64.StdEncoding.Encode(encoded, data)
+	return encoded
+}
+
+func decodeBase64(data []byte) ([]byte, error) {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(data)))
+	n, err := base64.StdEncoding.Decode(decoded, data)
+	return decoded[:n], err
+}
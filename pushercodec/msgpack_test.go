@@ -0,0 +1,71 @@
+package pushercodec
+
+import (
+	"math"
+	"testing"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"github.com/pusher/pusher-http-go/v5/pushertest"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+var _ pusher.Codec = Msgpack{}
+
+type message struct {
+	Text  string            `json:"text"`
+	Count uint64            `json:"count"`
+	Ratio float64           `json:"ratio"`
+	Tags  []string          `json:"tags"`
+	Meta  map[string]string `json:"meta,omitempty"`
+	Next  *message          `json:"next"`
+}
+
+var testMessage = message{
+	Text:  "<hello> & goodbye",
+	Count: math.MaxUint64,
+	Ratio: 0.25,
+	Tags:  []string{"a", "b"},
+	Meta:  map[string]string{"z": "1", "y": "2"},
+	Next:  &message{Tags: []string{}},
+}
+
+func TestMsgpackEncoding(t *testing.T) {
+	encoded, err := Msgpack{}.Marshal(map[string]int{"a": 1})
+	assert.NoError(t, err)
+	// 81 a1 61 01
+	assert.Equal(t, "gaFhAQ==", string(encoded))
+}
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	encoded, err := Msgpack{}.Marshal(testMessage)
+	assert.NoError(t, err)
+
+	var decoded message
+	assert.NoError(t, Msgpack{}.Unmarshal(encoded, &decoded))
+	assert.Equal(t, testMessage, decoded)
+
+	var fields map[string]interface{}
+	assert.NoError(t, Msgpack{}.Unmarshal(encoded, &fields))
+	assert.Contains(t, fields, "text")
+}
+
+func TestMsgpackMalformedData(t *testing.T) {
+	var v interface{}
+	assert.Error(t, Msgpack{}.Unmarshal([]byte("not base64!"), &v))
+	// A map announcing more entries than it has.
+	assert.Error(t, Msgpack{}.Unmarshal([]byte("jw=="), &v))
+}
+
+func TestMsgpackWithClient(t *testing.T) {
+	server := pushertest.NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+	client.Codec = Msgpack{}
+
+	assert.NoError(t, client.Trigger("my-channel", "my-event", map[string]int{"a": 1}))
+	assert.Equal(t, "gaFhAQ==", server.Events()[0].Data)
+
+	var decoded map[string]int
+	assert.NoError(t, client.DecodeClientEvent(pusher.ClientEvent{Event: "client-a", Data: "gaFhAQ=="}, &decoded))
+	assert.Equal(t, map[string]int{"a": 1}, decoded)
+}
@@ -0,0 +1,25 @@
+/*
+Package pushertest provides utilities for testing code which uses the Pusher
+HTTP API library, without any network access.
+
+Server is an in-process emulator of the Pusher HTTP API. It verifies requests
+signed by a `pusher.Client` exactly as Pusher does, enforces the same limits,
+and records every event triggered, so that tests can make assertions about
+them:
+
+	server := pushertest.NewServer("id", "key", "secret")
+	defer server.Close()
+
+	client := server.Client()
+	client.Trigger("my-channel", "my-event", "hello")
+
+	events := server.Events()
+	// events => [{Channel:my-channel Name:my-event Data:hello SocketID:}]
+
+Recorder is a `pusher.ClientInterface` for unit tests, which records events
+instead of sending them, returns canned responses and can inject errors.
+
+WebhookBuilder creates correctly signed webhooks, or deliberately broken ones,
+for testing webhook endpoints.
+*/
+package pushertest
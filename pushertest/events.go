@@ -0,0 +1,112 @@
+package pushertest
+
+import (
+	"sync"
+	"testing"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+)
+
+// Event is an event received by a Server or a Recorder. An event triggered on
+// several channels is recorded once per channel.
+type Event struct {
+	Channel  string
+	Name     string
+	Data     string
+	SocketID string
+}
+
+// eventLog records events, and provides assertions about them.
+type eventLog struct {
+	mutex  sync.Mutex
+	events []Event
+}
+
+func (l *eventLog) record(events ...Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = append(l.events, events...)
+}
+
+// Events returns every event received so far, in order.
+func (l *eventLog) Events() []Event {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]Event(nil), l.events...)
+}
+
+// EventsOn returns the events received on channel, in order.
+func (l *eventLog) EventsOn(channel string) []Event {
+	var events []Event
+	for _, event := range l.Events() {
+		if event.Channel == channel {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Reset forgets every received event.
+func (l *eventLog) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.events = nil
+}
+
+/*
+AssertTriggered fails the test unless an event named eventName was received on
+channel, and returns the last such event.
+
+	event := recorder.AssertTriggered(t, "my-channel", "my-event")
+	assert.Equal(t, `{"hello":"world"}`, event.Data)
+*/
+func (l *eventLog) AssertTriggered(t testing.TB, channel, eventName string) Event {
+	t.Helper()
+	events := l.EventsOn(channel)
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Name == eventName {
+			return events[i]
+		}
+	}
+	t.Errorf("Expected event '%s' to be triggered on channel '%s', got %+v", eventName, channel, l.Events())
+	return Event{}
+}
+
+// AssertNotTriggered fails the test if an event named eventName was received
+// on channel.
+func (l *eventLog) AssertNotTriggered(t testing.TB, channel, eventName string) {
+	t.Helper()
+	for _, event := range l.EventsOn(channel) {
+		if event.Name == eventName {
+			t.Errorf("Expected event '%s' not to be triggered on channel '%s'", eventName, channel)
+			return
+		}
+	}
+}
+
+// AssertEventCount fails the test unless exactly count events were received.
+func (l *eventLog) AssertEventCount(t testing.TB, count int) {
+	t.Helper()
+	if events := l.Events(); len(events) != count {
+		t.Errorf("Expected %d events to be triggered, got %d: %+v", count, len(events), events)
+	}
+}
+
+// encodeEventData encodes data in the same way as a `pusher.Client` whose Codec
+// is codec.
+func encodeEventData(data interface{}, codec pusher.Codec) (string, error) {
+	switch d := data.(type) {
+	case []byte:
+		return string(d), nil
+	case string:
+		return d, nil
+	}
+	if codec == nil {
+		codec = pusher.JSONCodec{}
+	}
+	dataBytes, err := codec.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(dataBytes), nil
+}
@@ -0,0 +1,243 @@
+package pushertest
+
+import (
+	"net/http"
+	"sync"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+)
+
+/*
+Recorder is a `pusher.ClientInterface` for unit tests. It records the events
+triggered through it instead of sending them, returns canned responses to
+channel queries, and can be told to fail any of its methods.
+
+Authentication, authorization and webhook verification are delegated to
+`Client`, since they do not make requests to Pusher.
+
+	recorder := pushertest.NewRecorder()
+	recorder.SetError("Trigger", errors.New("quota exceeded"))
+	recorder.Users["presence-room"] = &pusher.Users{List: []pusher.User{{ID: "1"}}}
+
+	notifier := Notifier{Pusher: recorder}
+	notifier.Notify("room")
+
+	recorder.AssertTriggered(t, "presence-room", "notification")
+*/
+type Recorder struct {
+	eventLog
+
+	// Client handles authentication, authorization and webhooks.
+	Client *pusher.Client
+	// Codec encodes the data of events in the same way as `pusher.Client.Codec`,
+	// as JSON if nil.
+	Codec pusher.Codec
+
+	// ChannelsList is returned by Channels, and an empty list if nil.
+	ChannelsList *pusher.ChannelsList
+	// ChannelStates are returned by Channel, and unoccupied channels for
+	// names which are missing.
+	ChannelStates map[string]*pusher.Channel
+	// Users are returned by GetChannelUsers, and empty lists for names which
+	// are missing.
+	Users map[string]*pusher.Users
+
+	errorsMutex sync.Mutex
+	errors      map[string]error
+}
+
+var _ pusher.ClientInterface = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder whose Client has the credentials "id", "key"
+// and "secret".
+func NewRecorder() *Recorder {
+	return &Recorder{
+		Client:        &pusher.Client{AppID: "id", Key: "key", Secret: "secret"},
+		ChannelStates: make(map[string]*pusher.Channel),
+		Users:         make(map[string]*pusher.Users),
+		errors:        make(map[string]error),
+	}
+}
+
+/*
+SetError makes the method of the given name, such as "Trigger" or "Channels",
+return err until SetError is called again with a nil error.
+*/
+func (r *Recorder) SetError(method string, err error) {
+	r.errorsMutex.Lock()
+	defer r.errorsMutex.Unlock()
+	if err == nil {
+		delete(r.errors, method)
+	} else {
+		r.errors[method] = err
+	}
+}
+
+func (r *Recorder) errorFor(method string) error {
+	r.errorsMutex.Lock()
+	defer r.errorsMutex.Unlock()
+	return r.errors[method]
+}
+
+func (r *Recorder) recordTrigger(channels []string, eventName string, data interface{}, socketID *string) error {
+	encodedData, err := encodeEventData(data, r.Codec)
+	if err != nil {
+		return err
+	}
+	events := make([]Event, len(channels))
+	for i, channel := range channels {
+		events[i] = Event{Channel: channel, Name: eventName, Data: encodedData}
+		if socketID != nil {
+			events[i].SocketID = *socketID
+		}
+	}
+	r.record(events...)
+	return nil
+}
+
+// Trigger implements pusher.ClientInterface.
+func (r *Recorder) Trigger(channel string, eventName string, data interface{}) error {
+	if err := r.errorFor("Trigger"); err != nil {
+		return err
+	}
+	return r.recordTrigger([]string{channel}, eventName, data, nil)
+}
+
+// TriggerWithParams implements pusher.ClientInterface.
+func (r *Recorder) TriggerWithParams(channel string, eventName string, data interface{}, params pusher.TriggerParams) (*pusher.TriggerChannelsList, error) {
+	if err := r.errorFor("TriggerWithParams"); err != nil {
+		return nil, err
+	}
+	if err := r.recordTrigger([]string{channel}, eventName, data, params.SocketID); err != nil {
+		return nil, err
+	}
+	return &pusher.TriggerChannelsList{}, nil
+}
+
+// TriggerMulti implements pusher.ClientInterface.
+func (r *Recorder) TriggerMulti(channels []string, eventName string, data interface{}) error {
+	if err := r.errorFor("TriggerMulti"); err != nil {
+		return err
+	}
+	return r.recordTrigger(channels, eventName, data, nil)
+}
+
+// TriggerMultiWithParams implements pusher.ClientInterface.
+func (r *Recorder) TriggerMultiWithParams(channels []string, eventName string, data interface{}, params pusher.TriggerParams) (*pusher.TriggerChannelsList, error) {
+	if err := r.errorFor("TriggerMultiWithParams"); err != nil {
+		return nil, err
+	}
+	if err := r.recordTrigger(channels, eventName, data, params.SocketID); err != nil {
+		return nil, err
+	}
+	return &pusher.TriggerChannelsList{}, nil
+}
+
+// TriggerBatch implements pusher.ClientInterface.
+func (r *Recorder) TriggerBatch(batch []pusher.Event) (*pusher.TriggerBatchChannelsList, error) {
+	if err := r.errorFor("TriggerBatch"); err != nil {
+		return nil, err
+	}
+	for _, event := range batch {
+		if err := r.recordTrigger([]string{event.Channel}, event.Name, event.Data, event.SocketID); err != nil {
+			return nil, err
+		}
+	}
+	return &pusher.TriggerBatchChannelsList{}, nil
+}
+
+// SendToUser implements pusher.ClientInterface.
+func (r *Recorder) SendToUser(userId string, eventName string, data interface{}) error {
+	if err := r.errorFor("SendToUser"); err != nil {
+		return err
+	}
+	return r.recordTrigger([]string{pusher.UserChannel(userId).String()}, eventName, data, nil)
+}
+
+// SendToUserWithParams implements pusher.ClientInterface.
+func (r *Recorder) SendToUserWithParams(userId string, eventName string, data interface{}, params pusher.TriggerParams) (*pusher.TriggerChannelsList, error) {
+	if err := r.errorFor("SendToUserWithParams"); err != nil {
+		return nil, err
+	}
+	if err := r.recordTrigger([]string{pusher.UserChannel(userId).String()}, eventName, data, params.SocketID); err != nil {
+		return nil, err
+	}
+	return &pusher.TriggerChannelsList{}, nil
+}
+
+// SendToUsers implements pusher.ClientInterface.
+func (r *Recorder) SendToUsers(userIds []string, eventName string, data interface{}) error {
+	if err := r.errorFor("SendToUsers"); err != nil {
+		return err
+	}
+	channels := make([]string, len(userIds))
+	for i, userId := range userIds {
+		channels[i] = pusher.UserChannel(userId).String()
+	}
+	return r.recordTrigger(channels, eventName, data, nil)
+}
+
+// Channels implements pusher.ClientInterface.
+func (r *Recorder) Channels(params pusher.ChannelsParams) (*pusher.ChannelsList, error) {
+	if err := r.errorFor("Channels"); err != nil {
+		return nil, err
+	}
+	if r.ChannelsList == nil {
+		return &pusher.ChannelsList{Channels: map[string]pusher.ChannelListItem{}}, nil
+	}
+	return r.ChannelsList, nil
+}
+
+// Channel implements pusher.ClientInterface.
+func (r *Recorder) Channel(name string, params pusher.ChannelParams) (*pusher.Channel, error) {
+	if err := r.errorFor("Channel"); err != nil {
+		return nil, err
+	}
+	if channel, ok := r.ChannelStates[name]; ok {
+		return channel, nil
+	}
+	return &pusher.Channel{Name: name}, nil
+}
+
+// GetChannelUsers implements pusher.ClientInterface.
+func (r *Recorder) GetChannelUsers(name string) (*pusher.Users, error) {
+	if err := r.errorFor("GetChannelUsers"); err != nil {
+		return nil, err
+	}
+	if users, ok := r.Users[name]; ok {
+		return users, nil
+	}
+	return &pusher.Users{List: []pusher.User{}}, nil
+}
+
+// AuthenticateUser implements pusher.ClientInterface.
+func (r *Recorder) AuthenticateUser(params []byte, userData map[string]interface{}) ([]byte, error) {
+	if err := r.errorFor("AuthenticateUser"); err != nil {
+		return nil, err
+	}
+	return r.Client.AuthenticateUser(params, userData)
+}
+
+// AuthorizePrivateChannel implements pusher.ClientInterface.
+func (r *Recorder) AuthorizePrivateChannel(params []byte) ([]byte, error) {
+	if err := r.errorFor("AuthorizePrivateChannel"); err != nil {
+		return nil, err
+	}
+	return r.Client.AuthorizePrivateChannel(params)
+}
+
+// AuthorizePresenceChannel implements pusher.ClientInterface.
+func (r *Recorder) AuthorizePresenceChannel(params []byte, member pusher.MemberData) ([]byte, error) {
+	if err := r.errorFor("AuthorizePresenceChannel"); err != nil {
+		return nil, err
+	}
+	return r.Client.AuthorizePresenceChannel(params, member)
+}
+
+// Webhook implements pusher.ClientInterface.
+func (r *Recorder) Webhook(header http.Header, body []byte) (*pusher.Webhook, error) {
+	if err := r.errorFor("Webhook"); err != nil {
+		return nil, err
+	}
+	return r.Client.Webhook(header, body)
+}
@@ -0,0 +1,157 @@
+package pushertest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+// failureRecorder records the failures reported by assertions.
+type failureRecorder struct {
+	testing.TB
+	failures int
+}
+
+func (f *failureRecorder) Helper() {}
+
+func (f *failureRecorder) Errorf(format string, args ...interface{}) {
+	f.failures++
+}
+
+func TestRecorderRecordsEvents(t *testing.T) {
+	recorder := NewRecorder()
+	var client pusher.ClientInterface = recorder
+
+	socketID := "1.1"
+	assert.NoError(t, client.Trigger("a", "event", map[string]int{"n": 1}))
+	_, err := client.TriggerMultiWithParams([]string{"b", "c"}, "event", "data", pusher.TriggerParams{SocketID: &socketID})
+	assert.NoError(t, err)
+	_, err = client.TriggerBatch([]pusher.Event{{Channel: "d", Name: "batched", Data: []byte("raw")}})
+	assert.NoError(t, err)
+	assert.NoError(t, client.SendToUser("1", "event", "hi"))
+
+	assert.Equal(t, []Event{
+		{Channel: "a", Name: "event", Data: `{"n":1}`},
+		{Channel: "b", Name: "event", Data: "data", SocketID: "1.1"},
+		{Channel: "c", Name: "event", Data: "data", SocketID: "1.1"},
+		{Channel: "d", Name: "batched", Data: "raw"},
+		{Channel: "#server-to-user-1", Name: "event", Data: "hi"},
+	}, recorder.Events())
+
+	event := recorder.AssertTriggered(t, "a", "event")
+	assert.Equal(t, `{"n":1}`, event.Data)
+	recorder.AssertNotTriggered(t, "a", "other")
+	recorder.AssertEventCount(t, 5)
+}
+
+func TestRecorderAssertionsFail(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Trigger("a", "event", "data")
+	failures := &failureRecorder{TB: t}
+
+	recorder.AssertTriggered(failures, "a", "other")
+	recorder.AssertNotTriggered(failures, "a", "event")
+	recorder.AssertEventCount(failures, 2)
+	assert.Equal(t, 3, failures.failures)
+}
+
+func TestRecorderInjectsErrors(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.SetError("Trigger", errors.New("quota exceeded"))
+
+	assert.EqualError(t, recorder.Trigger("a", "event", "data"), "quota exceeded")
+	assert.NoError(t, recorder.TriggerMulti([]string{"a"}, "event", "data"))
+	recorder.SetError("Trigger", nil)
+	assert.NoError(t, recorder.Trigger("a", "event", "data"))
+	recorder.AssertEventCount(t, 2)
+}
+
+func TestRecorderCannedResponses(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.ChannelsList = &pusher.ChannelsList{Channels: map[string]pusher.ChannelListItem{"presence-a": {UserCount: 1}}}
+	recorder.ChannelStates["presence-a"] = &pusher.Channel{Name: "presence-a", Occupied: true}
+	recorder.Users["presence-a"] = &pusher.Users{List: []pusher.User{{ID: "1"}}}
+
+	channels, err := recorder.Channels(pusher.ChannelsParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, recorder.ChannelsList, channels)
+
+	channel, err := recorder.Channel("presence-a", pusher.ChannelParams{})
+	assert.NoError(t, err)
+	assert.True(t, channel.Occupied)
+	channel, err = recorder.Channel("presence-b", pusher.ChannelParams{})
+	assert.NoError(t, err)
+	assert.False(t, channel.Occupied)
+
+	users, err := recorder.GetChannelUsers("presence-a")
+	assert.NoError(t, err)
+	assert.Equal(t, []pusher.User{{ID: "1"}}, users.List)
+	users, err = recorder.GetChannelUsers("presence-b")
+	assert.NoError(t, err)
+	assert.Empty(t, users.List)
+}
+
+func TestRecorderDelegatesAuthorization(t *testing.T) {
+	recorder := NewRecorder()
+	response, err := recorder.AuthorizePrivateChannel([]byte("channel_name=private-a&socket_id=1.1"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(response), `"auth":"key:`)
+}
+
+func TestRecorderSendToUsers(t *testing.T) {
+	recorder := NewRecorder()
+	assert.NoError(t, recorder.SendToUsers([]string{"1", "2"}, "event", "hi"))
+	recorder.AssertTriggered(t, "#server-to-user-1", "event")
+	recorder.AssertTriggered(t, "#server-to-user-2", "event")
+}
+
+func TestRecorderRecordsTopicEvents(t *testing.T) {
+	recorder := NewRecorder()
+	type update struct {
+		Status string `json:"status"`
+	}
+	topic := pusher.NewTopic[update](recorder, "order-updated", "private-orders-%s")
+
+	assert.NoError(t, topic.Publish(context.Background(), []interface{}{"1"}, update{Status: "shipped"}))
+	event := recorder.AssertTriggered(t, "private-orders-1", "order-updated")
+	assert.Equal(t, `{"status":"shipped"}`, event.Data)
+}
+
+func TestRecorderEncodesWithCodec(t *testing.T) {
+	recorder := NewRecorder()
+	assert.NoError(t, recorder.Trigger("a", "event", map[string]int{"a": 1}))
+
+	recorder.Codec = reversingCodec{}
+	type update struct {
+		Status string `json:"status"`
+	}
+	topic := pusher.NewTopic[update](recorder, "order-updated", "private-orders-%s")
+	assert.NoError(t, recorder.Trigger("b", "event", map[string]int{"a": 1}))
+	assert.NoError(t, topic.Publish(context.Background(), []interface{}{"1"}, update{Status: "shipped"}))
+	assert.NoError(t, recorder.Trigger("c", "event", "raw"))
+
+	events := recorder.Events()
+	assert.Equal(t, `{"a":1}`, events[0].Data)
+	assert.Equal(t, `}1:"a"{`, events[1].Data)
+	assert.Equal(t, `}"deppihs":"sutats"{`, events[2].Data)
+	assert.Equal(t, "raw", events[3].Data)
+}
+
+// reversingCodec encodes data as reversed JSON, to tell its output apart from
+// that of the default codec.
+type reversingCodec struct{}
+
+func (reversingCodec) Marshal(v interface{}) ([]byte, error) {
+	encoded, err := pusher.JSONCodec{}.Marshal(v)
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return encoded, err
+}
+
+func (reversingCodec) Unmarshal(data []byte, v interface{}) error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,393 @@
+package pushertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+)
+
+const (
+	maxTriggerableChannels = 100
+	defaultMaxPayloadKB    = 10
+	defaultMaxBatchSize    = 10
+)
+
+type channelState struct {
+	subscriptionCount int
+	userIDs           []string
+}
+
+/*
+Server is an in-process emulator of the Pusher HTTP API, serving
+`/apps/{id}/events`, `/apps/{id}/batch_events`, `/apps/{id}/channels`,
+`/apps/{id}/channels/{name}`, `/apps/{id}/channels/{name}/users` and
+`/apps/{id}/users/{id}/terminate_connections`.
+
+The state of channels is set up with `SetSubscriptionCount` and `SetUsers`.
+*/
+type Server struct {
+	AppID  string
+	Key    string
+	Secret string
+	URL    string // base URL of the server, e.g. http://127.0.0.1:12345
+
+	MaxPayloadKB int // maximum size of an event's data, 10 by default
+	MaxBatchSize int // maximum number of events in a batch, 10 by default
+
+	eventLog
+	server     *httptest.Server
+	mutex      sync.Mutex
+	channels   map[string]*channelState
+	terminated []string
+	now        func() time.Time
+}
+
+// NewServer starts a Server for the given application credentials. Call Close
+// when done.
+func NewServer(appID, key, secret string) *Server {
+	s := &Server{
+		AppID:    appID,
+		Key:      key,
+		Secret:   secret,
+		channels: make(map[string]*channelState),
+		now:      time.Now,
+	}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns a `pusher.Client` which sends its requests to the server.
+func (s *Server) Client() *pusher.Client {
+	u, _ := url.Parse(s.URL)
+	return &pusher.Client{
+		AppID:      s.AppID,
+		Key:        s.Key,
+		Secret:     s.Secret,
+		Host:       u.Host,
+		HTTPClient: s.server.Client(),
+	}
+}
+
+// SetSubscriptionCount sets the number of connections subscribed to channel.
+func (s *Server) SetSubscriptionCount(channel string, count int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channel(channel).subscriptionCount = count
+}
+
+// SetUsers sets the users subscribed to a presence-channel.
+func (s *Server) SetUsers(channel string, userIDs ...string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channel(channel).userIDs = append([]string(nil), userIDs...)
+}
+
+// TerminatedUsers returns the IDs of the users whose connections were
+// terminated, in order.
+func (s *Server) TerminatedUsers() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string(nil), s.terminated...)
+}
+
+func (s *Server) channel(name string) *channelState {
+	state, ok := s.channels[name]
+	if !ok {
+		state = &channelState{}
+		s.channels[name] = state
+	}
+	return state
+}
+
+func (s *Server) maxPayloadBytes() int {
+	if s.MaxPayloadKB == 0 {
+		return defaultMaxPayloadKB * 1024
+	}
+	return s.MaxPayloadKB * 1024
+}
+
+func (s *Server) maxBatchSize() int {
+	if s.MaxBatchSize == 0 {
+		return defaultMaxBatchSize
+	}
+	return s.MaxBatchSize
+}
+
+// requestError is an error which is reported to the client with a status code.
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, a ...interface{}) *requestError {
+	return &requestError{http.StatusBadRequest, fmt.Sprintf(format, a...)}
+}
+
+func (s *Server) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if rerr := s.authenticate(req); rerr != nil {
+		http.Error(res, rerr.message, rerr.status)
+		return
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		http.Error(res, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response, rerr := s.route(req, body)
+	if rerr != nil {
+		http.Error(res, rerr.message, rerr.status)
+		return
+	}
+	res.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(res).Encode(response)
+}
+
+func (s *Server) route(req *http.Request, body []byte) (interface{}, *requestError) {
+	prefix := "/apps/" + s.AppID + "/"
+	if !strings.HasPrefix(req.URL.Path, prefix) {
+		return nil, &requestError{http.StatusNotFound, "Unknown app"}
+	}
+	path := strings.Split(strings.TrimPrefix(req.URL.Path, prefix), "/")
+	query := req.URL.Query()
+
+	switch {
+	case req.Method == http.MethodPost && len(path) == 1 && path[0] == "events":
+		return s.triggerEvent(body)
+	case req.Method == http.MethodPost && len(path) == 1 && path[0] == "batch_events":
+		return s.triggerBatch(body)
+	case req.Method == http.MethodGet && len(path) == 1 && path[0] == "channels":
+		return s.channelsInfo(query)
+	case req.Method == http.MethodGet && len(path) == 2 && path[0] == "channels":
+		return s.channelInfo(path[1], query)
+	case req.Method == http.MethodGet && len(path) == 3 && path[0] == "channels" && path[2] == "users":
+		return s.channelUsers(path[1])
+	case req.Method == http.MethodPost && len(path) == 3 && path[0] == "users" && path[2] == "terminate_connections":
+		return s.terminateConnections(path[1])
+	}
+	return nil, &requestError{http.StatusNotFound, "Not found"}
+}
+
+// authenticate checks the request is signed in the same way as the Pusher API
+// does.
+func (s *Server) authenticate(req *http.Request) *requestError {
+	verifier := pusher.RequestVerifier{
+		Secrets: map[string]string{s.Key: s.Secret},
+		Now:     s.now,
+	}
+	if err := verifier.Verify(req); err == pusher.ErrRequestTooLarge {
+		return &requestError{http.StatusRequestEntityTooLarge, err.Error()}
+	} else if err != nil {
+		return &requestError{http.StatusUnauthorized, err.Error()}
+	}
+	return nil
+}
+
+type triggerRequest struct {
+	Name     string   `json:"name"`
+	Channels []string `json:"channels"`
+	Channel  string   `json:"channel"`
+	Data     string   `json:"data"`
+	SocketID string   `json:"socket_id"`
+	Info     string   `json:"info"`
+}
+
+func (s *Server) validateEvent(name string, channels []string, data string) *requestError {
+	if err := pusher.ValidateEventName(name, true); err != nil {
+		return badRequest("Invalid event name '%s'", name)
+	}
+	if len(channels) == 0 {
+		return badRequest("Missing channels")
+	}
+	if len(channels) > maxTriggerableChannels {
+		return badRequest("Cannot trigger on more than %d channels", maxTriggerableChannels)
+	}
+	for _, channel := range channels {
+		channelName, err := pusher.ParseChannelName(channel)
+		if err != nil {
+			return badRequest("Invalid channel name '%s'", channel)
+		}
+		if channelName.Kind() == pusher.EncryptedChannelKind && len(channels) > 1 {
+			return badRequest("Cannot trigger on multiple channels with an encrypted channel")
+		}
+	}
+	if len(data) > s.maxPayloadBytes() {
+		return &requestError{http.StatusRequestEntityTooLarge, fmt.Sprintf("Event data exceeds %d bytes", s.maxPayloadBytes())}
+	}
+	return nil
+}
+
+func (s *Server) triggerEvent(body []byte) (interface{}, *requestError) {
+	var trigger triggerRequest
+	if err := json.Unmarshal(body, &trigger); err != nil {
+		return nil, badRequest("Invalid JSON: %s", err)
+	}
+	if rerr := s.validateEvent(trigger.Name, trigger.Channels, trigger.Data); rerr != nil {
+		return nil, rerr
+	}
+
+	for _, channel := range trigger.Channels {
+		s.record(Event{Channel: channel, Name: trigger.Name, Data: trigger.Data, SocketID: trigger.SocketID})
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	response := map[string]interface{}{}
+	if trigger.Info != "" {
+		channels := make(map[string]interface{})
+		for _, channel := range trigger.Channels {
+			channels[channel] = s.attributes(channel, trigger.Info, false)
+		}
+		response["channels"] = channels
+	}
+	return response, nil
+}
+
+func (s *Server) triggerBatch(body []byte) (interface{}, *requestError) {
+	var batch struct {
+		Batch []triggerRequest `json:"batch"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, badRequest("Invalid JSON: %s", err)
+	}
+	if len(batch.Batch) > s.maxBatchSize() {
+		return nil, badRequest("Batch of %d events exceeds the limit of %d", len(batch.Batch), s.maxBatchSize())
+	}
+	withInfo := false
+	for _, event := range batch.Batch {
+		if rerr := s.validateEvent(event.Name, []string{event.Channel}, event.Data); rerr != nil {
+			return nil, rerr
+		}
+		withInfo = withInfo || event.Info != ""
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	attributes := make([]map[string]interface{}, len(batch.Batch))
+	for i, event := range batch.Batch {
+		s.record(Event{Channel: event.Channel, Name: event.Name, Data: event.Data, SocketID: event.SocketID})
+		attributes[i] = s.attributes(event.Channel, event.Info, false)
+	}
+
+	if withInfo {
+		return map[string]interface{}{"batch": attributes}, nil
+	}
+	return map[string]interface{}{}, nil
+}
+
+// attributes returns the requested info attributes of a channel. The caller
+// must hold the mutex.
+func (s *Server) attributes(channel, info string, withOccupied bool) map[string]interface{} {
+	state := s.channels[channel]
+	if state == nil {
+		state = &channelState{}
+	}
+	attributes := make(map[string]interface{})
+	if withOccupied {
+		attributes["occupied"] = state.subscriptionCount > 0 || len(state.userIDs) > 0
+	}
+	for _, attribute := range strings.Split(info, ",") {
+		switch attribute {
+		case "user_count":
+			if pusher.ChannelName(channel).Kind() == pusher.PresenceChannelKind {
+				attributes["user_count"] = len(state.userIDs)
+			}
+		case "subscription_count":
+			attributes["subscription_count"] = state.subscriptionCount
+		}
+	}
+	return attributes
+}
+
+func validateInfo(info, channelPrefix string) *requestError {
+	for _, attribute := range strings.Split(info, ",") {
+		switch attribute {
+		case "", "subscription_count":
+		case "user_count":
+			if !strings.HasPrefix(channelPrefix, "presence-") {
+				return badRequest("user_count may only be requested for presence-channels")
+			}
+		default:
+			return badRequest("Unknown info attribute '%s'", attribute)
+		}
+	}
+	return nil
+}
+
+func (s *Server) channelsInfo(query url.Values) (interface{}, *requestError) {
+	prefix := query.Get("filter_by_prefix")
+	info := query.Get("info")
+	if rerr := validateInfo(info, prefix); rerr != nil {
+		return nil, rerr
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	names := make([]string, 0, len(s.channels))
+	for name, state := range s.channels {
+		if strings.HasPrefix(name, prefix) && (state.subscriptionCount > 0 || len(state.userIDs) > 0) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	channels := make(map[string]interface{})
+	for _, name := range names {
+		channels[name] = s.attributes(name, info, false)
+	}
+	return map[string]interface{}{"channels": channels}, nil
+}
+
+func (s *Server) channelInfo(name string, query url.Values) (interface{}, *requestError) {
+	info := query.Get("info")
+	if rerr := validateInfo(info, name); rerr != nil {
+		return nil, rerr
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.attributes(name, info, true), nil
+}
+
+func (s *Server) channelUsers(name string) (interface{}, *requestError) {
+	if pusher.ChannelName(name).Kind() != pusher.PresenceChannelKind {
+		return nil, badRequest("Users can only be retrieved for presence-channels")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	users := []map[string]string{}
+	if state := s.channels[name]; state != nil {
+		for _, userID := range state.userIDs {
+			users = append(users, map[string]string{"id": userID})
+		}
+	}
+	return map[string]interface{}{"users": users}, nil
+}
+
+func (s *Server) terminateConnections(userID string) (interface{}, *requestError) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.terminated = append(s.terminated, userID)
+	return map[string]interface{}{}, nil
+}
@@ -0,0 +1,145 @@
+package pushertest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestServerRecordsTriggeredEvents(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+
+	socketID := "1234.12"
+	assert.NoError(t, client.Trigger("a", "event", "data"))
+	assert.NoError(t, client.TriggerMulti([]string{"b", "c"}, "event", map[string]string{"hello": "world"}))
+	_, err := client.TriggerBatch([]pusher.Event{{Channel: "d", Name: "batched", Data: "1", SocketID: &socketID}})
+	assert.NoError(t, err)
+	assert.NoError(t, client.SendToUser("123", "event", "data"))
+
+	assert.Equal(t, []Event{
+		{Channel: "a", Name: "event", Data: "data"},
+		{Channel: "b", Name: "event", Data: `{"hello":"world"}`},
+		{Channel: "c", Name: "event", Data: `{"hello":"world"}`},
+		{Channel: "d", Name: "batched", Data: "1", SocketID: "1234.12"},
+		{Channel: "#server-to-user-123", Name: "event", Data: "data"},
+	}, server.Events())
+
+	server.Reset()
+	assert.Empty(t, server.Events())
+}
+
+func TestServerRejectsInvalidSignature(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+	client.Secret = "wrong"
+
+	err := client.Trigger("a", "event", "data")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "401")
+	assert.Empty(t, server.Events())
+}
+
+func TestServerRejectsExpiredTimestamp(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	server.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	err := server.Client().Trigger("a", "event", "data")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), pusher.ErrRequestExpired.Error())
+}
+
+func TestServerEnforcesPayloadLimit(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+	client.OverrideMaxMessagePayloadKB = 20
+
+	err := client.Trigger("a", "event", strings.Repeat("a", 11*1024))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "413")
+
+	server.MaxPayloadKB = 20
+	assert.NoError(t, client.Trigger("a", "event", strings.Repeat("a", 11*1024)))
+}
+
+func TestServerEnforcesBatchLimit(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+
+	batch := make([]pusher.Event, 11)
+	for i := range batch {
+		batch[i] = pusher.Event{Channel: "a", Name: "event", Data: "data"}
+	}
+	_, err := server.Client().TriggerBatch(batch)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds the limit of 10")
+}
+
+func TestServerChannelQueries(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	client := server.Client()
+	server.SetUsers("presence-room", "1", "2")
+	server.SetSubscriptionCount("presence-room", 3)
+	server.SetSubscriptionCount("public", 1)
+
+	prefix := "presence-"
+	info := "user_count"
+	channels, err := client.Channels(pusher.ChannelsParams{FilterByPrefix: &prefix, Info: &info})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]pusher.ChannelListItem{"presence-room": {UserCount: 2}}, channels.Channels)
+
+	channels, err = client.Channels(pusher.ChannelsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, channels.Channels, 2)
+
+	channels, err = client.Channels(pusher.ChannelsParams{InfoAttributes: []pusher.InfoAttribute{pusher.InfoSubscriptionCount}})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]pusher.ChannelListItem{
+		"presence-room": {SubscriptionCount: 3},
+		"public":        {SubscriptionCount: 1},
+	}, channels.Channels)
+
+	_, err = client.Channels(pusher.ChannelsParams{Info: &info})
+	assert.Error(t, err)
+
+	attributes := "user_count,subscription_count"
+	channel, err := client.Channel("presence-room", pusher.ChannelParams{Info: &attributes})
+	assert.NoError(t, err)
+	assert.Equal(t, &pusher.Channel{Name: "presence-room", Occupied: true, UserCount: 2, SubscriptionCount: 3}, channel)
+
+	channel, err = client.Channel("empty", pusher.ChannelParams{})
+	assert.NoError(t, err)
+	assert.False(t, channel.Occupied)
+
+	users, err := client.GetChannelUsers("presence-room")
+	assert.NoError(t, err)
+	assert.Equal(t, &pusher.Users{List: []pusher.User{{ID: "1"}, {ID: "2"}}}, users)
+}
+
+func TestServerTriggerInfo(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+	server.SetSubscriptionCount("a", 5)
+
+	info := "subscription_count"
+	channels, err := server.Client().TriggerWithParams("a", "event", "data", pusher.TriggerParams{Info: &info})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, *channels.Channels["a"].SubscriptionCount)
+}
+
+func TestServerTerminateUserConnections(t *testing.T) {
+	server := NewServer("id", "key", "secret")
+	defer server.Close()
+
+	assert.NoError(t, server.Client().TerminateUsersConnections(context.Background(), []string{"1", "2"}))
+	assert.Equal(t, []string{"1", "2"}, server.TerminatedUsers())
+}
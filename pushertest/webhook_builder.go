@@ -0,0 +1,198 @@
+package pushertest
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+)
+
+/*
+WebhookBuilder creates webhooks signed in the same way as Pusher signs them, for
+testing webhook endpoints. Events of private-encrypted- channels are encrypted
+with `EncryptionMasterKeyBase64`.
+
+	header, body, err := pushertest.NewWebhookBuilder("key", "secret").
+		ChannelOccupied("my-channel").
+		MemberAdded("presence-room", "1").
+		Build()
+	webhook, err := client.Webhook(header, body)
+
+The negative paths of webhook verification are tested with `BadSignature`,
+`WrongKey` and `Stale`.
+*/
+type WebhookBuilder struct {
+	Key                       string
+	Secret                    string
+	EncryptionMasterKeyBase64 string
+
+	events       []pusher.WebhookEvent
+	time         time.Time
+	badSignature bool
+	wrongKey     bool
+}
+
+// webhookBody is the body of a webhook as Pusher sends it.
+type webhookBody struct {
+	TimeMs int            `json:"time_ms"`
+	Events []webhookEvent `json:"events"`
+}
+
+// webhookEvent carries the subscription count of subscription_count events
+// even when it is 0, and leaves it out of other events.
+type webhookEvent struct {
+	pusher.WebhookEvent
+	SubscriptionCount *int `json:"subscription_count,omitempty"`
+}
+
+// NewWebhookBuilder creates a WebhookBuilder for the given credentials.
+func NewWebhookBuilder(key, secret string) *WebhookBuilder {
+	return &WebhookBuilder{Key: key, Secret: secret}
+}
+
+// Event adds an event as is.
+func (b *WebhookBuilder) Event(event pusher.WebhookEvent) *WebhookBuilder {
+	b.events = append(b.events, event)
+	return b
+}
+
+// ChannelOccupied adds a channel_occupied event.
+func (b *WebhookBuilder) ChannelOccupied(channel string) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookChannelOccupied, Channel: channel})
+}
+
+// ChannelVacated adds a channel_vacated event.
+func (b *WebhookBuilder) ChannelVacated(channel string) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookChannelVacated, Channel: channel})
+}
+
+// MemberAdded adds a member_added event.
+func (b *WebhookBuilder) MemberAdded(channel, userID string) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookMemberAdded, Channel: channel, UserID: userID})
+}
+
+// MemberRemoved adds a member_removed event.
+func (b *WebhookBuilder) MemberRemoved(channel, userID string) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookMemberRemoved, Channel: channel, UserID: userID})
+}
+
+// ClientEvent adds a client_event event. Its data is encrypted when the
+// channel is a private-encrypted- channel.
+func (b *WebhookBuilder) ClientEvent(event pusher.ClientEvent) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{
+		Name:     pusher.WebhookClientEvent,
+		Channel:  event.Channel,
+		Event:    event.Event,
+		Data:     event.Data,
+		SocketID: event.SocketID,
+		UserID:   event.UserID,
+	})
+}
+
+// CacheMiss adds a cache_miss event.
+func (b *WebhookBuilder) CacheMiss(channel string) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookCacheMiss, Channel: channel})
+}
+
+// SubscriptionCount adds a subscription_count event.
+func (b *WebhookBuilder) SubscriptionCount(channel string, count int) *WebhookBuilder {
+	return b.Event(pusher.WebhookEvent{Name: pusher.WebhookSubscriptionCount, Channel: channel, SubscriptionCount: count})
+}
+
+// At sets the time_ms of the webhook, which is the current time by default.
+func (b *WebhookBuilder) At(t time.Time) *WebhookBuilder {
+	b.time = t
+	return b
+}
+
+// Stale sets the time_ms of the webhook to age ago.
+func (b *WebhookBuilder) Stale(age time.Duration) *WebhookBuilder {
+	return b.At(time.Now().Add(-age))
+}
+
+// BadSignature makes the webhook carry a signature which does not match its
+// body.
+func (b *WebhookBuilder) BadSignature() *WebhookBuilder {
+	b.badSignature = true
+	return b
+}
+
+// WrongKey makes the webhook carry an X-Pusher-Key other than Key.
+func (b *WebhookBuilder) WrongKey() *WebhookBuilder {
+	b.wrongKey = true
+	return b
+}
+
+// Build returns the header and body of the webhook, ready to be passed to
+// `Client.Webhook`.
+func (b *WebhookBuilder) Build() (http.Header, []byte, error) {
+	when := b.time
+	if when.IsZero() {
+		when = time.Now()
+	}
+
+	events := make([]webhookEvent, len(b.events))
+	for i, event := range b.events {
+		if event.Name == pusher.WebhookClientEvent && pusher.ChannelName(event.Channel).Kind() == pusher.EncryptedChannelKind {
+			client := pusher.Client{EncryptionMasterKeyBase64: b.EncryptionMasterKeyBase64}
+			encryptedMessage, err := client.EncryptForChannel(event.Channel, event.Data)
+			if err != nil {
+				return nil, nil, err
+			}
+			encryptedData, err := json.Marshal(encryptedMessage)
+			if err != nil {
+				return nil, nil, err
+			}
+			event.Data = string(encryptedData)
+		}
+		events[i] = webhookEvent{WebhookEvent: event}
+		if event.Name == pusher.WebhookSubscriptionCount {
+			count := event.SubscriptionCount
+			events[i].SubscriptionCount = &count
+		}
+	}
+
+	body, err := json.Marshal(webhookBody{
+		TimeMs: int(when.UnixNano() / int64(time.Millisecond)),
+		Events: events,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mac := hmac.New(sha256.New, []byte(b.Secret))
+	mac.Write(body)
+	if b.badSignature {
+		mac.Write([]byte("tampered"))
+	}
+	key := b.Key
+	if b.wrongKey {
+		key = "wrong-" + key
+	}
+
+	header := make(http.Header)
+	header.Set("Content-Type", "application/json")
+	header.Set("X-Pusher-Key", key)
+	header.Set("X-Pusher-Signature", hex.EncodeToString(mac.Sum(nil)))
+	return header, body, nil
+}
+
+// Request returns the webhook as a POST request to url, ready to be served by
+// a webhook endpoint such as a `pusher.WebhookHandler`.
+func (b *WebhookBuilder) Request(url string) (*http.Request, error) {
+	header, body, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header
+	return req, nil
+}
@@ -0,0 +1,99 @@
+package pushertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	pusher "github.com/pusher/pusher-http-go/v5"
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+const testMasterKey = "ZUhQVldIZzduRkdZVkJzS2pPRkRYV1JyaWJJUjJiMGI="
+
+func TestWebhookBuilderBuildsValidWebhooks(t *testing.T) {
+	client := pusher.Client{Key: "key", Secret: "secret", EncryptionMasterKeyBase64: testMasterKey}
+	builder := NewWebhookBuilder("key", "secret").
+		ChannelOccupied("a").
+		ChannelVacated("a").
+		MemberAdded("presence-a", "1").
+		MemberRemoved("presence-a", "1").
+		ClientEvent(pusher.ClientEvent{Channel: "private-a", Event: "client-a", Data: "hello", SocketID: "1.1"}).
+		ClientEvent(pusher.ClientEvent{Channel: "private-encrypted-a", Event: "client-a", Data: "secret hello"}).
+		CacheMiss("cache-a").
+		SubscriptionCount("a", 3).
+		At(time.Unix(1427233518, 0))
+	builder.EncryptionMasterKeyBase64 = testMasterKey
+
+	header, body, err := builder.Build()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "secret hello")
+
+	webhook, err := client.Webhook(header, body)
+	assert.NoError(t, err)
+	assert.Equal(t, 1427233518000, webhook.TimeMs)
+	assert.Len(t, webhook.Events, 8)
+	assert.Equal(t, pusher.ClientEvent{Channel: "private-a", Event: "client-a", Data: "hello", SocketID: "1.1"}, webhook.Events[4].Typed())
+	assert.Equal(t, "secret hello", webhook.Events[5].Data)
+	assert.NoError(t, webhook.Events[5].DecryptionError)
+	assert.Equal(t, pusher.SubscriptionCountEvent{Channel: "a", SubscriptionCount: 3}, webhook.Events[7].Typed())
+}
+
+func TestWebhookBuilderKeepsZeroSubscriptionCount(t *testing.T) {
+	_, body, err := NewWebhookBuilder("key", "secret").SubscriptionCount("a", 0).Build()
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), `"subscription_count":0`)
+
+	_, body, err = NewWebhookBuilder("key", "secret").ChannelOccupied("a").Build()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "subscription_count")
+}
+
+func TestWebhookBuilderNegativePaths(t *testing.T) {
+	client := pusher.Client{Key: "key", Secret: "secret"}
+
+	header, body, err := NewWebhookBuilder("key", "secret").ChannelOccupied("a").BadSignature().Build()
+	assert.NoError(t, err)
+	_, err = client.Webhook(header, body)
+	assert.Error(t, err)
+
+	header, body, err = NewWebhookBuilder("key", "secret").ChannelOccupied("a").WrongKey().Build()
+	assert.NoError(t, err)
+	_, err = client.Webhook(header, body)
+	assert.Error(t, err)
+
+	verifier := &pusher.WebhookVerifier{Client: &client, MaxSkew: time.Minute}
+	header, body, err = NewWebhookBuilder("key", "secret").ChannelOccupied("a").Stale(time.Hour).Build()
+	assert.NoError(t, err)
+	_, err = verifier.Webhook(header, body)
+	assert.Equal(t, pusher.ErrWebhookStale, err)
+
+	verifier.Now = func() time.Time { return time.Now().Add(-time.Hour) }
+	_, err = verifier.Webhook(header, body)
+	assert.NoError(t, err)
+}
+
+func TestWebhookBuilderRequiresMasterKeyForEncryptedEvents(t *testing.T) {
+	_, _, err := NewWebhookBuilder("key", "secret").
+		ClientEvent(pusher.ClientEvent{Channel: "private-encrypted-a", Event: "client-a", Data: "hello"}).
+		Build()
+	assert.Error(t, err)
+}
+
+func TestWebhookBuilderRequest(t *testing.T) {
+	client := pusher.Client{Key: "key", Secret: "secret"}
+	var vacated []string
+	handler := &pusher.WebhookHandler{Client: &client}
+	handler.OnChannelVacated = func(e pusher.ChannelVacatedEvent) error {
+		vacated = append(vacated, e.Channel)
+		return nil
+	}
+
+	req, err := NewWebhookBuilder("key", "secret").ChannelVacated("a").Request("http://example.com/webhook")
+	assert.NoError(t, err)
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"a"}, vacated)
+}
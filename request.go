@@ -2,7 +2,7 @@ package pusher
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -21,7 +21,15 @@ var headers = map[string]string{
 
 // change timeout to time.Duration
 func request(client *http.Client, method, url string, body []byte) ([]byte, error) {
+	return requestWithContext(context.Background(), client, method, url, body)
+}
+
+func requestWithContext(ctx context.Context, client *http.Client, method, url string, body []byte) ([]byte, error) {
 	req, err := http.NewRequest(method, url, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
 
 	for key, val := range headers {
 		req.Header.Set(http.CanonicalHeaderKey(key), val)
@@ -35,6 +43,19 @@ func request(client *http.Client, method, url string, body []byte) ([]byte, erro
 	return processResponse(resp)
 }
 
+/*
+RequestError is returned when the Pusher HTTP API responds with a non-2xx
+status code.
+*/
+type RequestError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("Status Code: %s - %s", strconv.Itoa(e.StatusCode), string(e.Body))
+}
+
 func processResponse(response *http.Response) ([]byte, error) {
 	responseBody, err := ioutil.ReadAll(response.Body)
 	if err != nil {
@@ -43,7 +64,5 @@ func processResponse(response *http.Response) ([]byte, error) {
 	if response.StatusCode >= 200 && response.StatusCode < 300 {
 		return responseBody, nil
 	}
-	message := fmt.Sprintf("Status Code: %s - %s", strconv.Itoa(response.StatusCode), string(responseBody))
-	err = errors.New(message)
-	return nil, err
+	return nil, &RequestError{StatusCode: response.StatusCode, Body: responseBody}
 }
@@ -0,0 +1,154 @@
+package pusher
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxRequestSkew is how far auth_timestamp may be from the current time,
+// unless a RequestVerifier specifies otherwise. It matches the Pusher API.
+const defaultMaxRequestSkew = 600 * time.Second
+
+// defaultMaxRequestBodyBytes is the largest request body a RequestVerifier
+// reads, unless MaxBodyBytes is set.
+const defaultMaxRequestBodyBytes = 1024 * 1024
+
+var (
+	// ErrRequestMalformed is returned for requests missing authentication
+	// parameters.
+	ErrRequestMalformed = errors.New("Request is missing authentication parameters")
+	// ErrRequestUnknownKey is returned for requests whose auth_key is not
+	// one of the known keys.
+	ErrRequestUnknownKey = errors.New("Request is signed with an unknown auth_key")
+	// ErrRequestExpired is returned for requests whose auth_timestamp is
+	// outside of the allowed window.
+	ErrRequestExpired = errors.New("Request auth_timestamp is outside of the allowed window")
+	// ErrRequestInvalidBodyMD5 is returned for requests whose body_md5 does
+	// not match their body.
+	ErrRequestInvalidBodyMD5 = errors.New("Request body_md5 does not match its body")
+	// ErrRequestInvalidSignature is returned for requests whose
+	// auth_signature does not match.
+	ErrRequestInvalidSignature = errors.New("Request auth_signature is invalid")
+	// ErrRequestTooLarge is returned for requests whose body is larger than
+	// the allowed size.
+	ErrRequestTooLarge = errors.New("Request body is too large")
+)
+
+/*
+RequestVerifier verifies requests signed in the same way as this library signs
+its requests to the Pusher HTTP API. `Secrets` maps each accepted auth_key to its
+secret, and `MaxSkew` bounds how far auth_timestamp may be from the current
+time, 600 seconds by default. Requests whose body is larger than `MaxBodyBytes`,
+1MB by default, are rejected without reading the rest of the body.
+*/
+type RequestVerifier struct {
+	Secrets      map[string]string
+	MaxSkew      time.Duration
+	MaxBodyBytes int64            // 1MB by default
+	Now          func() time.Time // the current time, time.Now by default
+}
+
+/*
+VerifyRequest verifies a request signed by this library, such as one received by
+a proxy in front of Pusher, with the default timestamp window. `secrets` maps
+each accepted auth_key to its secret.
+
+	err := pusher.VerifyRequest(req, map[string]string{"key": "secret"})
+	if err == pusher.ErrRequestExpired {
+		...
+	}
+*/
+func VerifyRequest(r *http.Request, secrets map[string]string) error {
+	verifier := RequestVerifier{Secrets: secrets}
+	return verifier.Verify(r)
+}
+
+/*
+Verify checks the auth_key, auth_timestamp, body_md5 and auth_signature of a
+request, returning one of the `ErrRequest...` errors if any is invalid. The body
+of the request is read, and replaced so that it can be read again.
+*/
+func (v *RequestVerifier) Verify(r *http.Request) error {
+	var body []byte
+	if r.Body != nil {
+		var err error
+		maxBodyBytes := v.maxBodyBytes()
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, maxBodyBytes+1))
+		r.Body.Close()
+		if err != nil {
+			return err
+		}
+		if int64(len(body)) > maxBodyBytes {
+			return ErrRequestTooLarge
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	params, err := url.ParseQuery(r.URL.RawQuery)
+	if err != nil {
+		return ErrRequestMalformed
+	}
+	signature := params.Get("auth_signature")
+	if signature == "" || params.Get("auth_version") != authVersion {
+		return ErrRequestMalformed
+	}
+
+	secret, ok := v.Secrets[params.Get("auth_key")]
+	if !ok {
+		return ErrRequestUnknownKey
+	}
+
+	timestamp, err := strconv.ParseInt(params.Get("auth_timestamp"), 10, 64)
+	if err != nil {
+		return ErrRequestMalformed
+	}
+	skew := v.currentTime().Sub(time.Unix(timestamp, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > v.maxSkew() {
+		return ErrRequestExpired
+	}
+
+	if _, hasBodyMD5 := params["body_md5"]; hasBodyMD5 || len(body) > 0 {
+		if !hmac.Equal([]byte(params.Get("body_md5")), []byte(md5Signature(body))) {
+			return ErrRequestInvalidBodyMD5
+		}
+	}
+
+	params.Del("auth_signature")
+	stringToSign := strings.Join([]string{r.Method, r.URL.Path, unescapeURL(params)}, "\n")
+	if !hmac.Equal([]byte(signature), []byte(hmacSignature(stringToSign, secret))) {
+		return ErrRequestInvalidSignature
+	}
+	return nil
+}
+
+func (v *RequestVerifier) currentTime() time.Time {
+	if v.Now == nil {
+		return time.Now()
+	}
+	return v.Now()
+}
+
+func (v *RequestVerifier) maxBodyBytes() int64 {
+	if v.MaxBodyBytes <= 0 {
+		return defaultMaxRequestBodyBytes
+	}
+	return v.MaxBodyBytes
+}
+
+func (v *RequestVerifier) maxSkew() time.Duration {
+	if v.MaxSkew <= 0 {
+		return defaultMaxRequestSkew
+	}
+	return v.MaxSkew
+}
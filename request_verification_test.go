@@ -0,0 +1,88 @@
+package pusher
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func newSignedRequest(t *testing.T, method string, timestamp time.Time, body []byte, params map[string]string) *http.Request {
+	u, err := createRequestURL(method, "example.com", "/apps/3/events", "key", "secret", strconv.FormatInt(timestamp.Unix(), 10), false, body, params, "")
+	assert.NoError(t, err)
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	assert.NoError(t, err)
+	return req
+}
+
+func TestVerifyRequestSuccess(t *testing.T) {
+	body := []byte(`{"name":"event","channels":["a"],"data":"hello"}`)
+	req := newSignedRequest(t, "POST", time.Now(), body, nil)
+
+	assert.NoError(t, VerifyRequest(req, map[string]string{"key": "secret"}))
+	readAgain, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, body, readAgain)
+
+	req = newSignedRequest(t, "GET", time.Now(), nil, map[string]string{"info": "user_count,subscription_count", "filter_by_prefix": "presence-"})
+	assert.NoError(t, VerifyRequest(req, map[string]string{"other": "x", "key": "secret"}))
+}
+
+func TestVerifyRequestFailures(t *testing.T) {
+	secrets := map[string]string{"key": "secret"}
+	body := []byte(`{"name":"event","channels":["a"],"data":"hello"}`)
+
+	req := newSignedRequest(t, "POST", time.Now(), body, nil)
+	assert.Equal(t, ErrRequestUnknownKey, VerifyRequest(req, map[string]string{"other": "secret"}))
+
+	req = newSignedRequest(t, "POST", time.Now().Add(-11*time.Minute), body, nil)
+	assert.Equal(t, ErrRequestExpired, VerifyRequest(req, secrets))
+
+	req = newSignedRequest(t, "POST", time.Now(), body, nil)
+	req.Body = ioutil.NopCloser(bytes.NewReader([]byte(`{"name":"other"}`)))
+	assert.Equal(t, ErrRequestInvalidBodyMD5, VerifyRequest(req, secrets))
+
+	req = newSignedRequest(t, "GET", time.Now(), nil, map[string]string{"info": "user_count"})
+	req.URL.RawQuery += "&filter_by_prefix=private-"
+	assert.Equal(t, ErrRequestInvalidSignature, VerifyRequest(req, secrets))
+
+	req = newSignedRequest(t, "GET", time.Now(), nil, nil)
+	req.URL.Path = "/apps/4/events"
+	assert.Equal(t, ErrRequestInvalidSignature, VerifyRequest(req, secrets))
+
+	req = newSignedRequest(t, "POST", time.Now(), body, nil)
+	assert.Equal(t, ErrRequestInvalidSignature, VerifyRequest(req, map[string]string{"key": "wrong"}))
+
+	req, _ = http.NewRequest("GET", "http://example.com/apps/3/channels", nil)
+	assert.Equal(t, ErrRequestMalformed, VerifyRequest(req, secrets))
+}
+
+func TestRequestVerifierWindow(t *testing.T) {
+	req := newSignedRequest(t, "GET", time.Unix(1000, 0), nil, nil)
+	verifier := RequestVerifier{
+		Secrets: map[string]string{"key": "secret"},
+		MaxSkew: time.Minute,
+		Now:     func() time.Time { return time.Unix(1059, 0) },
+	}
+	assert.NoError(t, verifier.Verify(req))
+
+	verifier.Now = func() time.Time { return time.Unix(1061, 0) }
+	assert.Equal(t, ErrRequestExpired, verifier.Verify(req))
+	verifier.Now = func() time.Time { return time.Unix(939, 0) }
+	assert.Equal(t, ErrRequestExpired, verifier.Verify(req))
+}
+
+func TestRequestVerifierRejectsLargeBodies(t *testing.T) {
+	body := []byte(`{"name":"event","channels":["a"],"data":"hello"}`)
+	verifier := RequestVerifier{Secrets: map[string]string{"key": "secret"}, MaxBodyBytes: int64(len(body))}
+
+	req := newSignedRequest(t, "POST", time.Now(), body, nil)
+	assert.NoError(t, verifier.Verify(req))
+
+	verifier.MaxBodyBytes--
+	req = newSignedRequest(t, "POST", time.Now(), body, nil)
+	assert.Equal(t, ErrRequestTooLarge, verifier.Verify(req))
+}
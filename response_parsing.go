@@ -19,11 +19,26 @@ type ChannelsList struct {
 
 // ChannelListItem represents an item within ChannelsList
 type ChannelListItem struct {
-	UserCount int `json:"user_count"`
+	UserCount         int `json:"user_count"`
+	SubscriptionCount int `json:"subscription_count"`
 }
 
+// InfoAttribute is an attribute of a channel which can be requested with
+// Channels and Channel.
+type InfoAttribute string
+
+const (
+	// InfoUserCount is the number of distinct users of a presence-channel.
+	InfoUserCount InfoAttribute = "user_count"
+	// InfoSubscriptionCount is the number of connections subscribed to a
+	// channel.
+	InfoSubscriptionCount InfoAttribute = "subscription_count"
+)
+
 type TriggerChannelsList struct {
 	Channels map[string]TriggerChannelListItem `json:"channels"`
+	// Skipped are the channels an UnoccupiedFilter did not trigger on.
+	Skipped []string `json:"-"`
 }
 
 type TriggerChannelListItem struct {
@@ -33,6 +48,9 @@ type TriggerChannelListItem struct {
 
 type TriggerBatchChannelsList struct {
 	Batch []TriggerBatchChannelListItem `json:"batch"`
+	// Skipped are the channels of the events an UnoccupiedFilter did not
+	// trigger.
+	Skipped []string `json:"-"`
 }
 
 type TriggerBatchChannelListItem struct {
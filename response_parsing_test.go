@@ -37,6 +37,19 @@ func TestParsingChannelsList(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestParsingChannelsListWithSubscriptionCount(t *testing.T) {
+	testJSON := []byte(`{"channels":{"presence-room":{"user_count":2,"subscription_count":3},"public":{"subscription_count":1}}}`)
+	expected := &ChannelsList{
+		Channels: map[string]ChannelListItem{
+			"presence-room": {UserCount: 2, SubscriptionCount: 3},
+			"public":        {SubscriptionCount: 1},
+		},
+	}
+	result, err := unmarshalledChannelsList(testJSON)
+	assert.Equal(t, expected, result)
+	assert.NoError(t, err)
+}
+
 func TestParsingChannel(t *testing.T) {
 	testJSON := []byte(`{"user_count":1,"occupied":true,"subscription_count":1}`)
 	channelName := "test"
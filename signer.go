@@ -0,0 +1,69 @@
+package pusher
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+/*
+Signer signs requests to the Pusher HTTP API in the same way as the client does,
+for endpoints which the library does not wrap, or to hand presigned URLs to other
+services. Pusher accepts a signed request for 600 seconds.
+
+	signer := client.Signer()
+	u, err := signer.SignURL("GET", "/apps/123/channels/presence-room", map[string]string{"info": "user_count"}, nil)
+*/
+type Signer struct {
+	Key     string
+	Secret  string
+	Host    string // host or host:port pair
+	Secure  bool   // true for HTTPS
+	Cluster string
+	Now     func() time.Time // the signing time, time.Now by default
+}
+
+// Signer returns a Signer with the client's credentials and host.
+func (c *Client) Signer() *Signer {
+	return &Signer{
+		Key:     c.Key,
+		Secret:  c.Secret,
+		Host:    c.Host,
+		Secure:  c.Secure,
+		Cluster: c.Cluster,
+	}
+}
+
+func (s *Signer) timestamp() string {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+	return strconv.FormatInt(now.Unix(), 10)
+}
+
+/*
+SignURL returns the signed URL of a request. The body, if any, must be sent
+unchanged with the request, since its MD5 is part of the signature.
+*/
+func (s *Signer) SignURL(method, path string, query map[string]string, body []byte) (string, error) {
+	return createRequestURL(method, s.Host, path, s.Key, s.Secret, s.timestamp(), s.Secure, body, query, s.Cluster)
+}
+
+// NewRequest returns a signed request, with the same headers as the requests
+// made by the client.
+func (s *Signer) NewRequest(method, path string, query map[string]string, body []byte) (*http.Request, error) {
+	signedURL, err := s.SignURL(method, path, query, body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, signedURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for key, val := range headers {
+		req.Header.Set(http.CanonicalHeaderKey(key), val)
+	}
+	return req, nil
+}
@@ -0,0 +1,39 @@
+package pusher
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestSignerSignURL(t *testing.T) {
+	signer := &Signer{
+		Key:    "278d425bdf160c739803",
+		Secret: "7ad3773142a6692b25b8",
+		Host:   "api.pusherapp.com",
+		Now:    func() time.Time { return time.Unix(1353088179, 0) },
+	}
+	body := []byte(`{"name":"foo","channels":["project-3"],"data":"{\"some\":\"data\"}"}`)
+
+	signedURL, err := signer.SignURL("POST", "/apps/3/events", nil, body)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://api.pusherapp.com/apps/3/events?auth_key=278d425bdf160c739803&auth_signature=da454824c97ba181a32ccc17a72625ba02771f50b50e1e7430e47a1f3f457e6c&auth_timestamp=1353088179&auth_version=1.0&body_md5=ec365a775a4cd0599faeb73354201b6f", signedURL)
+}
+
+func TestSignerNewRequestIsVerifiable(t *testing.T) {
+	client := Client{AppID: "3", Key: "key", Secret: "secret", Cluster: "eu", Secure: true}
+	signer := client.Signer()
+	body := []byte(`{"hello":"world"}`)
+
+	req, err := signer.NewRequest("POST", "/apps/3/users/1/terminate_connections", map[string]string{"extra": "1"}, body)
+	assert.NoError(t, err)
+	assert.Equal(t, "https", req.URL.Scheme)
+	assert.Equal(t, "api-eu.pusher.com", req.URL.Host)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.NoError(t, VerifyRequest(req, map[string]string{"key": "secret"}))
+
+	readBody, _ := ioutil.ReadAll(req.Body)
+	assert.Equal(t, body, readBody)
+}
@@ -0,0 +1,147 @@
+package pusher
+
+import (
+	"context"
+	"fmt"
+)
+
+/*
+Topic binds an event name and a channel pattern to the Go type of the event's
+data, so that the same type is the schema for publishing the event and for
+decoding it from webhooks.
+
+`ChannelPattern` is a format string which `Publish` fills in with its channel
+arguments, in the same way as `fmt.Sprintf`.
+
+	type OrderUpdate struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+
+	orders := pusher.NewTopic[OrderUpdate](client, "order-updated", "private-orders-%s")
+	err := orders.Publish(ctx, []interface{}{customerID}, OrderUpdate{ID: "42", Status: "shipped"})
+*/
+type Topic[T any] struct {
+	Client         ClientInterface
+	Event          string
+	ChannelPattern string
+	Codec          Codec // the client's Codec by default
+}
+
+// NewTopic creates a Topic publishing eventName through client on the channels
+// given by channelPattern.
+func NewTopic[T any](client ClientInterface, eventName, channelPattern string) *Topic[T] {
+	return &Topic[T]{Client: client, Event: eventName, ChannelPattern: channelPattern}
+}
+
+// Channel returns the channel of the topic for channelArgs.
+func (t *Topic[T]) Channel(channelArgs ...interface{}) string {
+	return fmt.Sprintf(t.ChannelPattern, channelArgs...)
+}
+
+// codecProvider is implemented by clients with a configurable Codec, and by the
+// wrappers of clients.
+type codecProvider interface {
+	eventCodec() Codec
+}
+
+// clientCodec returns the Codec of client, or nil for JSON.
+func clientCodec(client ClientInterface) Codec {
+	if provider, ok := client.(codecProvider); ok {
+		return provider.eventCodec()
+	}
+	return nil
+}
+
+// codec returns the Codec of the topic, or else of its client.
+func (t *Topic[T]) codec() Codec {
+	if t.Codec != nil {
+		return t.Codec
+	}
+	return clientCodec(t.Client)
+}
+
+// contextTriggerer is implemented by clients which can cancel triggers.
+type contextTriggerer interface {
+	validateChannelsAndTriggerWithContext(ctx context.Context, channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error)
+}
+
+/*
+Publish triggers the event of the topic with data on the channel given by
+channelArgs. Data is encoded by the client in the same way as by `Trigger`, or
+with the topic's `Codec` if it is set. The request is cancelled when ctx is done, if the client is a `*Client`; other clients are only
+prevented from triggering once ctx is done.
+*/
+func (t *Topic[T]) Publish(ctx context.Context, channelArgs []interface{}, data T) error {
+	_, err := t.PublishWithParams(ctx, channelArgs, data, TriggerParams{})
+	return err
+}
+
+// PublishWithParams is the same as Publish, except it allows additional
+// parameters to be specified in the same way as `TriggerWithParams`.
+func (t *Topic[T]) PublishWithParams(ctx context.Context, channelArgs []interface{}, data T, params TriggerParams) (*TriggerChannelsList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var payload interface{} = data
+	if t.Codec != nil {
+		encodedData, err := encodeEventData(data, t.Codec)
+		if err != nil {
+			return nil, err
+		}
+		payload = encodedData
+	}
+	channel := t.Channel(channelArgs...)
+	if client, ok := t.Client.(contextTriggerer); ok {
+		return client.validateChannelsAndTriggerWithContext(ctx, []string{channel}, t.Event, payload, params)
+	}
+	return t.Client.TriggerWithParams(channel, t.Event, payload, params)
+}
+
+/*
+Decode decodes the data of an event of the topic. Strings and byte slices are
+returned as is, and other types are decoded with the topic's codec, mirroring
+how `Publish` encodes them.
+*/
+func (t *Topic[T]) Decode(data string) (T, error) {
+	var value T
+	err := decodeEventData(data, &value, t.codec())
+	return value, err
+}
+
+/*
+DecodeClientEvent decodes the data of a client event received by a webhook.
+It returns an error if the event is not the event of the topic, or could not be
+decrypted.
+
+	err := webhook.Dispatch(pusher.WebhookEventHandler{
+		OnClientEvent: func(e pusher.ClientEvent) error {
+			update, err := orders.DecodeClientEvent(e)
+			...
+		},
+	})
+*/
+func (t *Topic[T]) DecodeClientEvent(event ClientEvent) (T, error) {
+	var value T
+	if event.DecryptionError != nil {
+		return value, event.DecryptionError
+	}
+	if event.Event != t.Event {
+		return value, fmt.Errorf("Event '%s' is not the event '%s' of the topic", event.Event, t.Event)
+	}
+	return t.Decode(event.Data)
+}
+
+/*
+DecodeWebhookEvent is the same as `DecodeClientEvent`, for a webhook event which
+has not been converted with `Typed`. It returns an error for events other than
+client events.
+*/
+func (t *Topic[T]) DecodeWebhookEvent(event WebhookEvent) (T, error) {
+	clientEvent, ok := event.Typed().(ClientEvent)
+	if !ok {
+		var value T
+		return value, fmt.Errorf("Webhook event '%s' is not a client event", event.Name)
+	}
+	return t.DecodeClientEvent(clientEvent)
+}
@@ -0,0 +1,139 @@
+package pusher
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type orderUpdate struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func TestTopicPublish(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		raw, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(raw, &body)
+		res.WriteHeader(200)
+		res.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := &Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	orders := NewTopic[orderUpdate](client, "order-updated", "private-orders-%s")
+
+	err := orders.Publish(context.Background(), []interface{}{"customer1"}, orderUpdate{ID: "42", Status: "shipped"})
+	assert.NoError(t, err)
+	assert.Equal(t, "order-updated", body["name"])
+	assert.Equal(t, []interface{}{"private-orders-customer1"}, body["channels"])
+	assert.Equal(t, `{"id":"42","status":"shipped"}`, body["data"])
+}
+
+func TestTopicPublishCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		t.Fatal("No request should reach the API")
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := &Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host}
+	orders := NewTopic[orderUpdate](client, "order-updated", "private-orders-%s")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := orders.Publish(ctx, []interface{}{"customer1"}, orderUpdate{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestTopicPublishValidatesChannel(t *testing.T) {
+	client := &Client{AppID: "id", Key: "key", Secret: "secret"}
+	orders := NewTopic[orderUpdate](client, "order-updated", "private-orders-%s")
+
+	err := orders.Publish(context.Background(), []interface{}{"bad id"}, orderUpdate{})
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestTopicDecodeClientEvent(t *testing.T) {
+	orders := NewTopic[orderUpdate](nil, "client-order-updated", "private-orders-%s")
+
+	update, err := orders.DecodeClientEvent(ClientEvent{Event: "client-order-updated", Data: `{"id":"42","status":"shipped"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, orderUpdate{ID: "42", Status: "shipped"}, update)
+
+	_, err = orders.DecodeClientEvent(ClientEvent{Event: "client-other", Data: `{}`})
+	assert.EqualError(t, err, "Event 'client-other' is not the event 'client-order-updated' of the topic")
+
+	_, err = orders.DecodeClientEvent(ClientEvent{Event: "client-order-updated", DecryptionError: ErrDecryptionFailed})
+	assert.Equal(t, ErrDecryptionFailed, err)
+
+	_, err = orders.DecodeClientEvent(ClientEvent{Event: "client-order-updated", Data: `not json`})
+	assert.Error(t, err)
+}
+
+func TestTopicDecodeWebhookEvent(t *testing.T) {
+	orders := NewTopic[orderUpdate](nil, "client-order-updated", "private-orders-%s")
+
+	update, err := orders.DecodeWebhookEvent(WebhookEvent{Name: WebhookClientEvent, Event: "client-order-updated", Data: `{"id":"42"}`})
+	assert.NoError(t, err)
+	assert.Equal(t, "42", update.ID)
+
+	_, err = orders.DecodeWebhookEvent(WebhookEvent{Name: WebhookChannelOccupied})
+	assert.EqualError(t, err, "Webhook event 'channel_occupied' is not a client event")
+}
+
+func TestTopicOfStrings(t *testing.T) {
+	messages := NewTopic[string](nil, "message", "chat-%d")
+	assert.Equal(t, "chat-7", messages.Channel(7))
+
+	message, err := messages.Decode("hello")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", message)
+}
+
+func TestTopicUsesClientCodec(t *testing.T) {
+	client := &Client{Codec: base64Codec{}}
+	orders := NewTopic[map[string]int](client, "client-order-updated", "private-orders-%s")
+
+	update, err := orders.Decode("eyJhIjoxfQ==")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, update)
+
+	orders.Codec = JSONCodec{}
+	update, err = orders.Decode(`{"a":1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, update)
+}
+
+func TestTopicUsesCodecOfWrappedClient(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		raw, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(raw, &body)
+		res.WriteHeader(200)
+		res.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	client := &Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host, Codec: base64Codec{}}
+	cache := &ChannelCache{ClientInterface: client}
+	filter := &UnoccupiedFilter{ClientInterface: cache, Occupancy: &OccupancyTracker{}}
+	orders := NewTopic[map[string]int](filter, "order-updated", "private-orders-%s")
+
+	err := orders.Publish(context.Background(), []interface{}{"customer1"}, map[string]int{"a": 1})
+	assert.NoError(t, err)
+	assert.Equal(t, "eyJhIjoxfQ==", body["data"])
+
+	update, err := orders.Decode("eyJhIjoxfQ==")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 1}, update)
+}
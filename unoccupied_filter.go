@@ -0,0 +1,135 @@
+package pusher
+
+/*
+OccupancySource knows whether channels are occupied. It is implemented by
+`*OccupancyTracker` and `*ChannelCache`.
+*/
+type OccupancySource interface {
+	// Occupancy reports whether a channel is occupied, and whether that is
+	// known at all.
+	Occupancy(channel string) (occupied, known bool)
+}
+
+var (
+	_ OccupancySource = (*OccupancyTracker)(nil)
+	_ OccupancySource = (*ChannelCache)(nil)
+)
+
+/*
+UnoccupiedFilter is a `ClientInterface` which does not trigger events on
+channels that `Occupancy` knows to be unoccupied, saving the messages nobody
+would receive. Events on channels whose occupancy is not known are triggered.
+
+The skipped channels are reported in the `Skipped` field of the results of
+`TriggerWithParams`, `TriggerMultiWithParams` and `TriggerBatch`. Results of
+`TriggerBatch` keep an empty item in place of each skipped event, so that they
+stay in the order of the batch.
+
+	tracker := &pusher.OccupancyTracker{Client: client}
+	filter := &pusher.UnoccupiedFilter{ClientInterface: client, Occupancy: tracker}
+	result, err := filter.TriggerMultiWithParams(channels, "update", data, pusher.TriggerParams{})
+	log.Printf("skipped %d channels", len(result.Skipped))
+*/
+type UnoccupiedFilter struct {
+	ClientInterface
+	Occupancy OccupancySource
+}
+
+var _ ClientInterface = (*UnoccupiedFilter)(nil)
+
+// eventCodec returns the Codec of the wrapped client.
+func (f *UnoccupiedFilter) eventCodec() Codec {
+	return clientCodec(f.ClientInterface)
+}
+
+func (f *UnoccupiedFilter) unoccupied(channel string) bool {
+	occupied, known := f.Occupancy.Occupancy(channel)
+	return known && !occupied
+}
+
+// filter splits channels into those to trigger on and those to skip.
+func (f *UnoccupiedFilter) filter(channels []string) (occupied, skipped []string) {
+	for _, channel := range channels {
+		if f.unoccupied(channel) {
+			skipped = append(skipped, channel)
+		} else {
+			occupied = append(occupied, channel)
+		}
+	}
+	return occupied, skipped
+}
+
+// Trigger implements ClientInterface, skipping an unoccupied channel.
+func (f *UnoccupiedFilter) Trigger(channel string, eventName string, data interface{}) error {
+	_, err := f.TriggerWithParams(channel, eventName, data, TriggerParams{})
+	return err
+}
+
+// TriggerWithParams implements ClientInterface, skipping an unoccupied channel.
+func (f *UnoccupiedFilter) TriggerWithParams(channel string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	if f.unoccupied(channel) {
+		return &TriggerChannelsList{Skipped: []string{channel}}, nil
+	}
+	return f.ClientInterface.TriggerWithParams(channel, eventName, data, params)
+}
+
+// TriggerMulti implements ClientInterface, skipping unoccupied channels.
+func (f *UnoccupiedFilter) TriggerMulti(channels []string, eventName string, data interface{}) error {
+	_, err := f.TriggerMultiWithParams(channels, eventName, data, TriggerParams{})
+	return err
+}
+
+// TriggerMultiWithParams implements ClientInterface, skipping unoccupied
+// channels.
+func (f *UnoccupiedFilter) TriggerMultiWithParams(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	occupied, skipped := f.filter(channels)
+	if len(occupied) == 0 {
+		return &TriggerChannelsList{Skipped: skipped}, nil
+	}
+	result, err := f.ClientInterface.TriggerMultiWithParams(occupied, eventName, data, params)
+	if err != nil {
+		return nil, err
+	}
+	result.Skipped = skipped
+	return result, nil
+}
+
+// TriggerBatch implements ClientInterface, skipping the events of unoccupied
+// channels.
+func (f *UnoccupiedFilter) TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error) {
+	var (
+		events  []Event
+		skipped []string
+		sent    []bool
+	)
+	for _, event := range batch {
+		unoccupied := f.unoccupied(event.Channel)
+		if unoccupied {
+			skipped = append(skipped, event.Channel)
+		} else {
+			events = append(events, event)
+		}
+		sent = append(sent, !unoccupied)
+	}
+	if len(events) == 0 {
+		return &TriggerBatchChannelsList{Skipped: skipped}, nil
+	}
+
+	result, err := f.ClientInterface.TriggerBatch(events)
+	if err != nil {
+		return nil, err
+	}
+	if len(skipped) > 0 && len(result.Batch) == len(events) {
+		items := make([]TriggerBatchChannelListItem, len(batch))
+		next := 0
+		for i := range batch {
+			if sent[i] {
+				items[i] = result.Batch[next]
+				next++
+			}
+		}
+		result.Batch = items
+	}
+	result.Skipped = skipped
+	return result, nil
+}
@@ -0,0 +1,109 @@
+package pusher
+
+import (
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+type triggerClient struct {
+	*Client
+	triggered []string
+}
+
+func (c *triggerClient) TriggerWithParams(channel string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	c.triggered = append(c.triggered, channel)
+	return &TriggerChannelsList{}, nil
+}
+
+func (c *triggerClient) TriggerMultiWithParams(channels []string, eventName string, data interface{}, params TriggerParams) (*TriggerChannelsList, error) {
+	c.triggered = append(c.triggered, channels...)
+	return &TriggerChannelsList{}, nil
+}
+
+func (c *triggerClient) TriggerBatch(batch []Event) (*TriggerBatchChannelsList, error) {
+	result := &TriggerBatchChannelsList{}
+	for i, event := range batch {
+		c.triggered = append(c.triggered, event.Channel)
+		count := i + 1
+		result.Batch = append(result.Batch, TriggerBatchChannelListItem{SubscriptionCount: &count})
+	}
+	return result, nil
+}
+
+func newTestUnoccupiedFilter() (*UnoccupiedFilter, *triggerClient) {
+	tracker := &OccupancyTracker{}
+	tracker.Occupy("occupied")
+	tracker.Occupy("vacated")
+	tracker.Vacate("vacated")
+	upstream := &triggerClient{}
+	return &UnoccupiedFilter{ClientInterface: upstream, Occupancy: tracker}, upstream
+}
+
+func TestUnoccupiedFilterTrigger(t *testing.T) {
+	filter, upstream := newTestUnoccupiedFilter()
+
+	result, err := filter.TriggerWithParams("vacated", "event", "data", TriggerParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vacated"}, result.Skipped)
+
+	assert.NoError(t, filter.Trigger("unknown", "event", "data"))
+	assert.NoError(t, filter.Trigger("vacated", "event", "data"))
+	assert.Equal(t, []string{"unknown"}, upstream.triggered)
+}
+
+func TestUnoccupiedFilterTriggerMulti(t *testing.T) {
+	filter, upstream := newTestUnoccupiedFilter()
+
+	result, err := filter.TriggerMultiWithParams([]string{"occupied", "vacated", "unknown"}, "event", "data", TriggerParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vacated"}, result.Skipped)
+	assert.Equal(t, []string{"occupied", "unknown"}, upstream.triggered)
+
+	result, err = filter.TriggerMultiWithParams([]string{"vacated"}, "event", "data", TriggerParams{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vacated"}, result.Skipped)
+	assert.Len(t, upstream.triggered, 2)
+}
+
+func TestUnoccupiedFilterTriggerBatch(t *testing.T) {
+	filter, upstream := newTestUnoccupiedFilter()
+
+	result, err := filter.TriggerBatch([]Event{
+		{Channel: "vacated", Name: "event", Data: "1"},
+		{Channel: "occupied", Name: "event", Data: "2"},
+		{Channel: "unknown", Name: "event", Data: "3"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vacated"}, result.Skipped)
+	assert.Equal(t, []string{"occupied", "unknown"}, upstream.triggered)
+	assert.Len(t, result.Batch, 3)
+	assert.Nil(t, result.Batch[0].SubscriptionCount)
+	assert.Equal(t, 1, *result.Batch[1].SubscriptionCount)
+	assert.Equal(t, 2, *result.Batch[2].SubscriptionCount)
+}
+
+func TestChannelCacheOccupancy(t *testing.T) {
+	upstream := &countingClient{Client: &Client{}}
+	cache := &ChannelCache{ClientInterface: upstream, ChannelTTL: time.Minute, ChannelsTTL: time.Minute}
+
+	_, known := cache.Occupancy("my-channel")
+	assert.False(t, known)
+
+	cache.Channel("my-channel", ChannelParams{})
+	occupied, known := cache.Occupancy("my-channel")
+	assert.True(t, occupied)
+	assert.True(t, known)
+
+	prefix := "presence-"
+	cache.Channels(ChannelsParams{FilterByPrefix: &prefix})
+	occupied, known = cache.Occupancy("presence-room")
+	assert.True(t, occupied)
+	assert.True(t, known)
+	occupied, known = cache.Occupancy("presence-empty")
+	assert.False(t, occupied)
+	assert.True(t, known)
+	_, known = cache.Occupancy("private-room")
+	assert.False(t, known)
+}
@@ -12,8 +12,10 @@ import (
 )
 
 var channelValidationRegex = regexp.MustCompile("^[-a-zA-Z0-9_=@,.;]+$")
+var channelIllegalCharacterRegex = regexp.MustCompile("[^-a-zA-Z0-9_=@,.;]")
 var socketIDValidationRegex = regexp.MustCompile(`\A\d+\.\d+\z`)
 var maxChannelNameSize = 200
+var maxEventNameSize = 200
 
 func jsonMarshalToString(data interface{}) (result string, err error) {
 	var _result []byte
@@ -58,27 +60,14 @@ func validUserId(userId string) bool {
 	return length > 0 && length < maxChannelNameSize
 }
 
-func validChannel(channel string) bool {
-	if len(channel) > maxChannelNameSize || !channelValidationRegex.MatchString(channel) {
-		return false
-	}
-	return true
-}
-
-func channelsAreValid(channels []string) bool {
-	for _, channel := range channels {
-		if !validChannel(channel) {
-			return false
-		}
-	}
-	return true
+// validUserIdPath reports whether userId can be put into the path of a request
+// as is, without changing the path or adding a query to it.
+func validUserIdPath(userId string) bool {
+	return validUserId(userId) && !strings.ContainsAny(userId, "/?#%") && !strings.Contains(userId, "..")
 }
 
 func isEncryptedChannel(channel string) bool {
-	if strings.HasPrefix(channel, "private-encrypted-") {
-		return true
-	}
-	return false
+	return ChannelName(channel).Kind() == EncryptedChannelKind
 }
 
 func validateUserData(userData map[string]interface{}) (err error) {
@@ -103,3 +92,31 @@ func validateSocketID(socketID *string) (err error) {
 	}
 	return errors.New("socket_id invalid")
 }
+
+func joinInfo(info *string, attributes []InfoAttribute) string {
+	var parts []string
+	if info != nil && *info != "" {
+		parts = append(parts, *info)
+	}
+	for _, attribute := range attributes {
+		parts = append(parts, string(attribute))
+	}
+	return strings.Join(parts, ",")
+}
+
+// validateInfoAttributes checks attributes can be requested for channels whose
+// name, or name prefix, is channel.
+func validateInfoAttributes(attributes []InfoAttribute, channel string) error {
+	for _, attribute := range attributes {
+		switch attribute {
+		case InfoSubscriptionCount:
+		case InfoUserCount:
+			if !strings.HasPrefix(channel, presenceChannelPrefix) {
+				return fmt.Errorf("The %s attribute is only available for presence-channels, not '%s'", attribute, channel)
+			}
+		default:
+			return fmt.Errorf("Unknown info attribute '%s'", attribute)
+		}
+	}
+	return nil
+}
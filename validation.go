@@ -0,0 +1,201 @@
+package pusher
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationRule is a rule which channel and event names must follow.
+type ValidationRule string
+
+const (
+	// RuleEmpty is broken by empty names.
+	RuleEmpty ValidationRule = "empty"
+	// RuleTooLong is broken by names longer than 200 characters.
+	RuleTooLong ValidationRule = "too_long"
+	// RuleCharset is broken by channel names with characters other than
+	// `a-z`, `A-Z`, `0-9`, `_`, `-`, `=`, `@`, `,`, `.` and `;`.
+	RuleCharset ValidationRule = "charset"
+	// RuleMissingID is broken by channel names which are only a prefix, such
+	// as "presence-".
+	RuleMissingID ValidationRule = "missing_id"
+	// RuleEncryptedInMulti is broken by private-encrypted- channels
+	// triggered on together with other channels.
+	RuleEncryptedInMulti ValidationRule = "encrypted_in_multi"
+	// RuleReservedPrefix is broken by event names starting with "pusher:" or
+	// "pusher_internal:", which are reserved for Pusher, or with "client-",
+	// unless Client.AllowClientEvents is set.
+	RuleReservedPrefix ValidationRule = "reserved_prefix"
+)
+
+// reservedEventPrefixes are the prefixes of the events of the Pusher protocol.
+var reservedEventPrefixes = []string{"pusher:", "pusher_internal:"}
+
+// clientEventPrefix is the prefix of events triggered by clients.
+const clientEventPrefix = "client-"
+
+// Violation is a name which broke a ValidationRule.
+type Violation struct {
+	Field    string // "channel" or "event"
+	Name     string
+	Index    int // the index of the channel in the call, or of the event in a batch
+	Rule     ValidationRule
+	Position int // the byte offset in Name at which the rule was broken, or -1 for the whole name
+}
+
+func (v Violation) String() string {
+	switch v.Rule {
+	case RuleEmpty:
+		return fmt.Sprintf("%s name is empty", v.Field)
+	case RuleTooLong:
+		return fmt.Sprintf("%s '%s' is longer than %d characters", v.Field, v.Name, maxChannelNameSize)
+	case RuleCharset:
+		return fmt.Sprintf("%s '%s' has the illegal character '%s' at position %d", v.Field, v.Name, characterAt(v.Name, v.Position), v.Position)
+	case RuleMissingID:
+		return fmt.Sprintf("%s '%s' has nothing after its prefix", v.Field, v.Name)
+	case RuleReservedPrefix:
+		return fmt.Sprintf("%s '%s' starts with a reserved prefix", v.Field, v.Name)
+	case RuleEncryptedInMulti:
+		return fmt.Sprintf("%s '%s' is encrypted, and you cannot trigger to multiple channels when using encrypted channels", v.Field, v.Name)
+	}
+	return fmt.Sprintf("%s '%s' breaks the rule %s at position %d", v.Field, v.Name, v.Rule, v.Position)
+}
+
+// characterAt returns the character starting at a byte offset of name, or the
+// escaped byte if it is not valid UTF-8.
+func characterAt(name string, position int) string {
+	r, size := utf8.DecodeRuneInString(name[position:])
+	if r == utf8.RuneError && size <= 1 {
+		return fmt.Sprintf("\\x%02x", name[position])
+	}
+	return string(r)
+}
+
+/*
+ValidationError is returned for calls with invalid channel or event names. It
+lists every violation, rather than only the first.
+
+	err := client.TriggerMulti(channels, "update", data)
+	if validationErr, ok := err.(*pusher.ValidationError); ok {
+		for _, violation := range validationErr.Violations {
+			log.Printf("skipping %s: %s", violation.Name, violation.Rule)
+		}
+	}
+*/
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
+	}
+	return "Invalid names: " + strings.Join(messages, "; ")
+}
+
+// validationErrorOf returns a ValidationError for violations, or nil if there
+// are none.
+func validationErrorOf(violations []Violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// channelViolation returns the first rule broken by a channel name, if any.
+func channelViolation(channel string, index int) (Violation, bool) {
+	violation := Violation{Field: "channel", Name: channel, Index: index, Position: -1}
+	name := ChannelName(channel)
+	switch {
+	case channel == "":
+		violation.Rule, violation.Position = RuleEmpty, 0
+	case name.Kind() == UserChannelKind:
+		if name.ID() == "" {
+			violation.Rule, violation.Position = RuleMissingID, len(channel)
+		} else if !validUserId(name.ID()) {
+			violation.Rule, violation.Position = RuleTooLong, len(userChannelPrefix)+maxChannelNameSize-1
+		} else {
+			return violation, false
+		}
+	case len(channel) > maxChannelNameSize:
+		violation.Rule, violation.Position = RuleTooLong, maxChannelNameSize
+	case !channelValidationRegex.MatchString(channel):
+		violation.Rule, violation.Position = RuleCharset, channelIllegalCharacterRegex.FindStringIndex(channel)[0]
+	case name.ID() == "":
+		violation.Rule, violation.Position = RuleMissingID, len(channel)
+	default:
+		return violation, false
+	}
+	return violation, true
+}
+
+// eventViolation returns the first rule broken by an event name, if any.
+func eventViolation(eventName string, index int, allowClientEvents bool) (Violation, bool) {
+	violation := Violation{Field: "event", Name: eventName, Index: index, Position: -1}
+	switch {
+	case eventName == "":
+		violation.Rule, violation.Position = RuleEmpty, 0
+	case len(eventName) > maxEventNameSize:
+		violation.Rule, violation.Position = RuleTooLong, maxEventNameSize
+	case hasReservedEventPrefix(eventName, allowClientEvents):
+		violation.Rule, violation.Position = RuleReservedPrefix, 0
+	default:
+		return violation, false
+	}
+	return violation, true
+}
+
+func hasReservedEventPrefix(eventName string, allowClientEvents bool) bool {
+	for _, prefix := range reservedEventPrefixes {
+		if strings.HasPrefix(eventName, prefix) {
+			return true
+		}
+	}
+	return !allowClientEvents && strings.HasPrefix(eventName, clientEventPrefix)
+}
+
+/*
+ValidateEventName checks an event name in the same way as the methods of
+`Client` which trigger events, returning a `*ValidationError` if it is empty,
+longer than 200 characters, or starts with a reserved prefix. Names starting with
+"client-" are only allowed if allowClientEvents is set.
+*/
+func ValidateEventName(eventName string, allowClientEvents bool) error {
+	if violation, ok := eventViolation(eventName, 0, allowClientEvents); ok {
+		return validationErrorOf([]Violation{violation})
+	}
+	return nil
+}
+
+// validateTrigger checks the channels and event name of a trigger.
+func validateTrigger(channels []string, eventName string, allowClientEvents bool) error {
+	var violations []Violation
+	for i, channel := range channels {
+		if violation, ok := channelViolation(channel, i); ok {
+			violations = append(violations, violation)
+		} else if len(channels) > 1 && isEncryptedChannel(channel) {
+			violations = append(violations, Violation{Field: "channel", Name: channel, Index: i, Rule: RuleEncryptedInMulti, Position: -1})
+		}
+	}
+	if violation, ok := eventViolation(eventName, 0, allowClientEvents); ok {
+		violations = append(violations, violation)
+	}
+	return validationErrorOf(violations)
+}
+
+// validateBatch checks the channels and event names of a batch, indexing
+// violations by event.
+func validateBatch(batch []Event, allowClientEvents bool) error {
+	var violations []Violation
+	for i, event := range batch {
+		if violation, ok := channelViolation(event.Channel, i); ok {
+			violations = append(violations, violation)
+		}
+		if violation, ok := eventViolation(event.Name, i, allowClientEvents); ok {
+			violations = append(violations, violation)
+		}
+	}
+	return validationErrorOf(violations)
+}
@@ -0,0 +1,108 @@
+package pusher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func TestValidationErrorListsEveryViolation(t *testing.T) {
+	client := Client{AppID: "id", Key: "key", Secret: "secret"}
+	longChannel := strings.Repeat("a", 201)
+
+	err := client.TriggerMulti([]string{"ok", "bad channel", longChannel, "presence-", "", "private-encrypted-a"}, "event", "data")
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "channel", Name: "bad channel", Index: 1, Rule: RuleCharset, Position: 3},
+		{Field: "channel", Name: longChannel, Index: 2, Rule: RuleTooLong, Position: 200},
+		{Field: "channel", Name: "presence-", Index: 3, Rule: RuleMissingID, Position: 9},
+		{Field: "channel", Name: "", Index: 4, Rule: RuleEmpty, Position: 0},
+		{Field: "channel", Name: "private-encrypted-a", Index: 5, Rule: RuleEncryptedInMulti, Position: -1},
+	}}, err)
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := &ValidationError{Violations: []Violation{
+		{Field: "channel", Name: "a b", Index: 0, Rule: RuleCharset, Position: 1},
+		{Field: "channel", Name: "presence-", Index: 1, Rule: RuleMissingID, Position: 9},
+		{Field: "event", Name: "", Index: 0, Rule: RuleEmpty, Position: 0},
+	}}
+	assert.EqualError(t, err, "Invalid names: channel 'a b' has the illegal character ' ' at position 1; "+
+		"channel 'presence-' has nothing after its prefix; event name is empty")
+}
+
+func TestValidationErrorNamesMultiByteCharacters(t *testing.T) {
+	_, err := ParseChannelName("café")
+	assert.EqualError(t, err, "Invalid names: channel 'café' has the illegal character 'é' at position 3")
+
+	err = &ValidationError{Violations: []Violation{{Field: "channel", Name: "a\xffb", Rule: RuleCharset, Position: 1}}}
+	assert.EqualError(t, err, "Invalid names: channel 'a\xffb' has the illegal character '\\xff' at position 1")
+}
+
+func TestEventNameValidation(t *testing.T) {
+	client := Client{AppID: "id", Key: "key", Secret: "secret"}
+	longEvent := strings.Repeat("e", 201)
+
+	err := client.Trigger("channel", "", "data")
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "event", Name: "", Index: 0, Rule: RuleEmpty, Position: 0},
+	}}, err)
+
+	err = client.Trigger("channel", longEvent, "data")
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "event", Name: longEvent, Index: 0, Rule: RuleTooLong, Position: 200},
+	}}, err)
+
+	err = client.SendToUser("123", "", "data")
+	assert.IsType(t, &ValidationError{}, err)
+
+	err = client.SendToUsers([]string{"123"}, "", "data")
+	assert.IsType(t, &ValidationError{}, err)
+}
+
+func TestBatchValidationIndexesByEvent(t *testing.T) {
+	client := Client{AppID: "id", Key: "key", Secret: "secret"}
+
+	_, err := client.TriggerBatch([]Event{
+		{Channel: "ok", Name: "event", Data: "data"},
+		{Channel: "bad^", Name: "", Data: "data"},
+	})
+	assert.Equal(t, &ValidationError{Violations: []Violation{
+		{Field: "channel", Name: "bad^", Index: 1, Rule: RuleCharset, Position: 3},
+		{Field: "event", Name: "", Index: 1, Rule: RuleEmpty, Position: 0},
+	}}, err)
+}
+
+func TestEventNameReservedPrefixes(t *testing.T) {
+	client := Client{AppID: "id", Key: "key", Secret: "secret"}
+
+	for _, eventName := range []string{"pusher:subscribe", "pusher_internal:member_added", "client-typing"} {
+		err := client.Trigger("channel", eventName, "data")
+		assert.Equal(t, &ValidationError{Violations: []Violation{
+			{Field: "event", Name: eventName, Index: 0, Rule: RuleReservedPrefix, Position: 0},
+		}}, err, eventName)
+	}
+
+	_, err := client.TriggerBatch([]Event{{Channel: "channel", Name: "pusher:ping", Data: "data"}})
+	assert.EqualError(t, err, "Invalid names: event 'pusher:ping' starts with a reserved prefix")
+}
+
+func TestAllowClientEvents(t *testing.T) {
+	assert.Error(t, ValidateEventName("client-typing", false))
+	assert.NoError(t, ValidateEventName("client-typing", true))
+	assert.Error(t, ValidateEventName("pusher:ping", true))
+	assert.NoError(t, ValidateEventName("typing", false))
+
+	server := httptest.NewServer(http.HandlerFunc(func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+		fmt.Fprintf(res, "{}")
+	}))
+	defer server.Close()
+	u, _ := url.Parse(server.URL)
+	client := Client{AppID: "id", Key: "key", Secret: "secret", Host: u.Host, AllowClientEvents: true}
+	assert.NoError(t, client.Trigger("private-channel", "client-typing", "data"))
+}
@@ -4,6 +4,17 @@ import (
 	"encoding/json"
 )
 
+// Names of the events that can be delivered in a webhook.
+const (
+	WebhookChannelOccupied   = "channel_occupied"
+	WebhookChannelVacated    = "channel_vacated"
+	WebhookMemberAdded       = "member_added"
+	WebhookMemberRemoved     = "member_removed"
+	WebhookClientEvent       = "client_event"
+	WebhookCacheMiss         = "cache_miss"
+	WebhookSubscriptionCount = "subscription_count"
+)
+
 // Webhook is the parsed form of a valid webhook received by the server.
 type Webhook struct {
 	TimeMs int            `json:"time_ms"` // the timestamp of the request
@@ -13,12 +24,181 @@ type Webhook struct {
 // WebhookEvent is the parsed form of a valid webhook event received by the
 // server.
 type WebhookEvent struct {
-	Name     string `json:"name"`                // the type of the event
-	Channel  string `json:"channel"`             // the channel on which it was sent
-	Event    string `json:"event,omitempty"`     // the name of the event
-	Data     string `json:"data,omitempty"`      // the data associated with the event
-	SocketID string `json:"socket_id,omitempty"` // the socket_id of the sending socket
-	UserID   string `json:"user_id,omitempty"`   // the user_id of a member who has joined or vacated a presence-channel
+	Name              string `json:"name"`                         // the type of the event
+	Channel           string `json:"channel"`                      // the channel on which it was sent
+	Event             string `json:"event,omitempty"`              // the name of the event
+	Data              string `json:"data,omitempty"`               // the data associated with the event
+	SocketID          string `json:"socket_id,omitempty"`          // the socket_id of the sending socket
+	UserID            string `json:"user_id,omitempty"`            // the user_id of a member who has joined or vacated a presence-channel
+	SubscriptionCount int    `json:"subscription_count,omitempty"` // the number of subscribers of a subscription_count event
+
+	// EncryptionMasterKeyID is the ID of the EncryptionMasterKey which
+	// decrypted an event of a private-encrypted- channel. It is empty when
+	// the client's own master key was used.
+	EncryptionMasterKeyID string `json:"-"`
+	// DecryptionError is set when an event of a private-encrypted- channel
+	// could not be decrypted, in which case Data is left encrypted.
+	DecryptionError error `json:"-"`
+}
+
+// ChannelOccupiedEvent is sent when a channel gains its first subscriber.
+type ChannelOccupiedEvent struct {
+	Channel string
+}
+
+// ChannelVacatedEvent is sent when a channel loses its last subscriber.
+type ChannelVacatedEvent struct {
+	Channel string
+}
+
+// MemberAddedEvent is sent when a user joins a presence-channel.
+type MemberAddedEvent struct {
+	Channel string
+	UserID  string
+}
+
+// MemberRemovedEvent is sent when a user leaves a presence-channel.
+type MemberRemovedEvent struct {
+	Channel string
+	UserID  string
+}
+
+// ClientEvent is sent when a client triggers an event on a private- or
+// presence-channel. UserID is only set for presence-channels, and
+// DecryptionError only for private-encrypted- channels whose event could not
+// be decrypted.
+type ClientEvent struct {
+	Channel         string
+	Event           string
+	Data            string
+	SocketID        string
+	UserID          string
+	DecryptionError error
+}
+
+// CacheMissEvent is sent when a client subscribes to a cache-channel which
+// has no cached event.
+type CacheMissEvent struct {
+	Channel string
+}
+
+// SubscriptionCountEvent is sent when the number of subscribers of a channel
+// changes.
+type SubscriptionCountEvent struct {
+	Channel           string
+	SubscriptionCount int
+}
+
+/*
+Typed returns the event as one of `ChannelOccupiedEvent`, `ChannelVacatedEvent`,
+`MemberAddedEvent`, `MemberRemovedEvent`, `ClientEvent`, `CacheMissEvent` or
+`SubscriptionCountEvent`, depending on its `Name`. Events of an unknown kind are
+returned unchanged as a `WebhookEvent`.
+
+	switch e := event.Typed().(type) {
+	case pusher.MemberAddedEvent:
+		fmt.Println(e.UserID, "joined", e.Channel)
+	case pusher.ClientEvent:
+		fmt.Println(e.Event, e.Data)
+	}
+*/
+func (e WebhookEvent) Typed() interface{} {
+	switch e.Name {
+	case WebhookChannelOccupied:
+		return ChannelOccupiedEvent{Channel: e.Channel}
+	case WebhookChannelVacated:
+		return ChannelVacatedEvent{Channel: e.Channel}
+	case WebhookMemberAdded:
+		return MemberAddedEvent{Channel: e.Channel, UserID: e.UserID}
+	case WebhookMemberRemoved:
+		return MemberRemovedEvent{Channel: e.Channel, UserID: e.UserID}
+	case WebhookClientEvent:
+		return ClientEvent{
+			Channel:         e.Channel,
+			Event:           e.Event,
+			Data:            e.Data,
+			SocketID:        e.SocketID,
+			UserID:          e.UserID,
+			DecryptionError: e.DecryptionError,
+		}
+	case WebhookCacheMiss:
+		return CacheMissEvent{Channel: e.Channel}
+	case WebhookSubscriptionCount:
+		return SubscriptionCountEvent{Channel: e.Channel, SubscriptionCount: e.SubscriptionCount}
+	}
+	return e
+}
+
+/*
+WebhookEventHandler holds one callback per kind of webhook event. Callbacks
+left as nil are skipped.
+*/
+type WebhookEventHandler struct {
+	OnChannelOccupied   func(ChannelOccupiedEvent) error
+	OnChannelVacated    func(ChannelVacatedEvent) error
+	OnMemberAdded       func(MemberAddedEvent) error
+	OnMemberRemoved     func(MemberRemovedEvent) error
+	OnClientEvent       func(ClientEvent) error
+	OnCacheMiss         func(CacheMissEvent) error
+	OnSubscriptionCount func(SubscriptionCountEvent) error
+	OnUnknown           func(WebhookEvent) error // events of a kind this library does not know about
+}
+
+func (h WebhookEventHandler) handle(event WebhookEvent) error {
+	switch e := event.Typed().(type) {
+	case ChannelOccupiedEvent:
+		if h.OnChannelOccupied != nil {
+			return h.OnChannelOccupied(e)
+		}
+	case ChannelVacatedEvent:
+		if h.OnChannelVacated != nil {
+			return h.OnChannelVacated(e)
+		}
+	case MemberAddedEvent:
+		if h.OnMemberAdded != nil {
+			return h.OnMemberAdded(e)
+		}
+	case MemberRemovedEvent:
+		if h.OnMemberRemoved != nil {
+			return h.OnMemberRemoved(e)
+		}
+	case ClientEvent:
+		if h.OnClientEvent != nil {
+			return h.OnClientEvent(e)
+		}
+	case CacheMissEvent:
+		if h.OnCacheMiss != nil {
+			return h.OnCacheMiss(e)
+		}
+	case SubscriptionCountEvent:
+		if h.OnSubscriptionCount != nil {
+			return h.OnSubscriptionCount(e)
+		}
+	case WebhookEvent:
+		if h.OnUnknown != nil {
+			return h.OnUnknown(e)
+		}
+	}
+	return nil
+}
+
+/*
+Dispatch passes every event of the webhook, in order, to the matching callback
+of `handler`. It stops at, and returns, the first error returned by a callback.
+
+	err := webhook.Dispatch(pusher.WebhookEventHandler{
+		OnMemberAdded: func(e pusher.MemberAddedEvent) error {
+			return roster.Add(e.Channel, e.UserID)
+		},
+	})
+*/
+func (w *Webhook) Dispatch(handler WebhookEventHandler) error {
+	for _, event := range w.Events {
+		if err := handler.handle(event); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func unmarshalledWebhook(requestBody []byte) (*Webhook, error) {
@@ -0,0 +1,113 @@
+package pusher
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultMaxWebhookBodyBytes is the largest webhook body a WebhookHandler
+// reads, unless MaxBodyBytes is set.
+const defaultMaxWebhookBodyBytes = 1024 * 1024
+
+/*
+WebhookHandler is an `http.Handler` that verifies webhooks with
+`Client.Webhook` and passes their events to the callbacks of the embedded
+`WebhookEventHandler`.
+
+It responds with 200 only once every callback has returned without an error.
+Any other outcome is answered with a non-2xx status, so that Pusher retries
+the delivery.
+
+When `Verifier` is set, it is used instead of `Client` to verify webhooks.
+Duplicates are then acknowledged with 200 without invoking any callback, and
+webhooks whose callbacks fail are forgotten so that their redelivery is handled.
+Stale webhooks are acknowledged with 202 without invoking any callback, since
+every redelivery of them would be just as stale. When the `SeenStore` of the
+Verifier fails, the webhook is answered with 503 so that Pusher retries it.
+`OnError` is called with the errors of the store, including when a webhook could
+not be forgotten, as its redelivery will then be discarded as a duplicate.
+
+	handler := &pusher.WebhookHandler{Client: client}
+	handler.OnChannelOccupied = func(e pusher.ChannelOccupiedEvent) error {
+		return producer.Start(e.Channel)
+	}
+	handler.OnChannelVacated = func(e pusher.ChannelVacatedEvent) error {
+		return producer.Stop(e.Channel)
+	}
+	http.Handle("/pusher/webhook", handler)
+*/
+type WebhookHandler struct {
+	Client       *Client
+	Verifier     *WebhookVerifier // optional, adds replay protection
+	MaxBodyBytes int64            // 1MB by default
+	OnError      func(error)      // optional, for errors of the Verifier's SeenStore
+	WebhookEventHandler
+}
+
+func (h *WebhookHandler) maxBodyBytes() int64 {
+	if h.MaxBodyBytes <= 0 {
+		return defaultMaxWebhookBodyBytes
+	}
+	return h.MaxBodyBytes
+}
+
+func (h *WebhookHandler) verify(header http.Header, body []byte) (*Webhook, error) {
+	if h.Verifier != nil {
+		return h.Verifier.Webhook(header, body)
+	}
+	return h.Client.Webhook(header, body)
+}
+
+func (h *WebhookHandler) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		res.Header().Set("Allow", http.MethodPost)
+		http.Error(res, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	maxBodyBytes := h.maxBodyBytes()
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxBodyBytes+1))
+	if err != nil {
+		http.Error(res, "Could not read webhook", http.StatusBadRequest)
+		return
+	}
+	if int64(len(body)) > maxBodyBytes {
+		http.Error(res, "Webhook too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	webhook, err := h.verify(req.Header, body)
+	if err == ErrWebhookDuplicate {
+		res.WriteHeader(http.StatusOK)
+		return
+	}
+	if err == ErrWebhookStale {
+		res.WriteHeader(http.StatusAccepted)
+		return
+	}
+	var storeErr *WebhookSeenStoreError
+	if errors.As(err, &storeErr) {
+		if h.OnError != nil {
+			h.OnError(err)
+		}
+		http.Error(res, "Could not check webhook", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(res, "Invalid webhook", http.StatusUnauthorized)
+		return
+	}
+
+	if err := webhook.Dispatch(h.WebhookEventHandler); err != nil {
+		if h.Verifier != nil {
+			if err := h.Verifier.Forget(req.Header); err != nil && h.OnError != nil {
+				h.OnError(err)
+			}
+		}
+		http.Error(res, "Could not handle webhook", http.StatusInternalServerError)
+		return
+	}
+	res.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,80 @@
+package pusher
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func newSignedWebhookRequest(client Client, body string) *http.Request {
+	req := httptest.NewRequest("POST", "/webhook", bytes.NewBufferString(body))
+	req.Header.Set("X-Pusher-Key", client.Key)
+	req.Header.Set("X-Pusher-Signature", hmacSignature(body, client.Secret))
+	return req
+}
+
+func TestWebhookHandlerSuccess(t *testing.T) {
+	client := setUpClient()
+	var occupied []string
+	handler := &WebhookHandler{Client: &client}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		occupied = append(occupied, e.Channel)
+		return nil
+	}
+
+	res := httptest.NewRecorder()
+	body := `{"time_ms":1,"events":[{"name":"channel_occupied","channel":"a"},{"name":"channel_vacated","channel":"b"}]}`
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, []string{"a"}, occupied)
+}
+
+func TestWebhookHandlerInvalidSignature(t *testing.T) {
+	client := setUpClient()
+	handler := &WebhookHandler{Client: &client}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		t.Fatal("No callback should be invoked")
+		return nil
+	}
+
+	req := newSignedWebhookRequest(client, `{"events":[{"name":"channel_occupied","channel":"a"}]}`)
+	req.Header.Set("X-Pusher-Signature", "bad")
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	assert.Equal(t, http.StatusUnauthorized, res.Code)
+}
+
+func TestWebhookHandlerCallbackError(t *testing.T) {
+	client := setUpClient()
+	handler := &WebhookHandler{Client: &client}
+	handler.OnMemberAdded = func(e MemberAddedEvent) error {
+		return errors.New("database unavailable")
+	}
+
+	res := httptest.NewRecorder()
+	body := `{"time_ms":1,"events":[{"name":"member_added","channel":"presence-a","user_id":"1"}]}`
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+}
+
+func TestWebhookHandlerBodyTooLarge(t *testing.T) {
+	client := setUpClient()
+	handler := &WebhookHandler{Client: &client, MaxBodyBytes: 10}
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, `{"time_ms":1,"events":[]}`))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, res.Code)
+}
+
+func TestWebhookHandlerMethodNotAllowed(t *testing.T) {
+	client := setUpClient()
+	handler := &WebhookHandler{Client: &client}
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, httptest.NewRequest("GET", "/webhook", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, res.Code)
+}
@@ -1,6 +1,7 @@
 package pusher
 
 import (
+	"errors"
 	"net/http"
 	"testing"
 
@@ -76,3 +77,74 @@ func TestWebhookUnmarshalling(t *testing.T) {
 	assert.Equal(t, expected, result)
 	assert.NoError(t, err)
 }
+
+func TestWebhookUnmarshallingSubscriptionCount(t *testing.T) {
+	body := []byte(`{"time_ms":1427233518933,"events":[{"name":"subscription_count","channel":"my-channel","subscription_count":42}]}`)
+	result, err := unmarshalledWebhook(body)
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result.Events[0].SubscriptionCount)
+	assert.Equal(t, SubscriptionCountEvent{Channel: "my-channel", SubscriptionCount: 42}, result.Events[0].Typed())
+}
+
+func TestWebhookEventTyped(t *testing.T) {
+	assert.Equal(t, ChannelOccupiedEvent{Channel: "a"}, WebhookEvent{Name: "channel_occupied", Channel: "a"}.Typed())
+	assert.Equal(t, ChannelVacatedEvent{Channel: "a"}, WebhookEvent{Name: "channel_vacated", Channel: "a"}.Typed())
+	assert.Equal(t, MemberAddedEvent{Channel: "presence-a", UserID: "1"}, WebhookEvent{Name: "member_added", Channel: "presence-a", UserID: "1"}.Typed())
+	assert.Equal(t, MemberRemovedEvent{Channel: "presence-a", UserID: "1"}, WebhookEvent{Name: "member_removed", Channel: "presence-a", UserID: "1"}.Typed())
+	assert.Equal(t, CacheMissEvent{Channel: "cache-a"}, WebhookEvent{Name: "cache_miss", Channel: "cache-a"}.Typed())
+	assert.Equal(t,
+		ClientEvent{Channel: "private-a", Event: "client-yolo", Data: "{}", SocketID: "1.1"},
+		WebhookEvent{Name: "client_event", Channel: "private-a", Event: "client-yolo", Data: "{}", SocketID: "1.1"}.Typed(),
+	)
+
+	unknown := WebhookEvent{Name: "something_new", Channel: "a"}
+	assert.Equal(t, unknown, unknown.Typed())
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	webhook := &Webhook{
+		Events: []WebhookEvent{
+			{Name: "channel_occupied", Channel: "a"},
+			{Name: "member_added", Channel: "presence-a", UserID: "1"},
+			{Name: "client_event", Channel: "private-a", Event: "client-yolo"},
+			{Name: "something_new", Channel: "a"},
+		},
+	}
+
+	var seen []string
+	err := webhook.Dispatch(WebhookEventHandler{
+		OnChannelOccupied: func(e ChannelOccupiedEvent) error {
+			seen = append(seen, "occupied:"+e.Channel)
+			return nil
+		},
+		OnMemberAdded: func(e MemberAddedEvent) error {
+			seen = append(seen, "added:"+e.UserID)
+			return nil
+		},
+		OnUnknown: func(e WebhookEvent) error {
+			seen = append(seen, "unknown:"+e.Name)
+			return nil
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"occupied:a", "added:1", "unknown:something_new"}, seen)
+}
+
+func TestWebhookDispatchStopsOnError(t *testing.T) {
+	webhook := &Webhook{
+		Events: []WebhookEvent{
+			{Name: "channel_vacated", Channel: "a"},
+			{Name: "channel_vacated", Channel: "b"},
+		},
+	}
+
+	calls := 0
+	err := webhook.Dispatch(WebhookEventHandler{
+		OnChannelVacated: func(e ChannelVacatedEvent) error {
+			calls++
+			return errors.New("boom")
+		},
+	})
+	assert.EqualError(t, err, "boom")
+	assert.Equal(t, 1, calls)
+}
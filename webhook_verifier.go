@@ -0,0 +1,219 @@
+package pusher
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrWebhookStale is returned for webhooks whose `time_ms` is outside of
+	// the allowed skew.
+	ErrWebhookStale = errors.New("Webhook timestamp is outside of the allowed skew")
+	// ErrWebhookDuplicate is returned for webhooks which have been seen before.
+	ErrWebhookDuplicate = errors.New("Webhook has already been received")
+)
+
+/*
+WebhookSeenStoreError is returned when the `SeenStore` of a WebhookVerifier
+fails, in which case the webhook could not be checked for duplicates. Unlike
+the other errors of verification, it does not mean the webhook is invalid.
+*/
+type WebhookSeenStoreError struct {
+	Err error
+}
+
+func (e *WebhookSeenStoreError) Error() string {
+	return "Could not check the webhook for duplicates: " + e.Err.Error()
+}
+
+// Unwrap returns the error of the store.
+func (e *WebhookSeenStoreError) Unwrap() error {
+	return e.Err
+}
+
+// WebhookCredentials is a key/secret pair which webhooks may be signed with.
+type WebhookCredentials struct {
+	Key    string
+	Secret string
+}
+
+/*
+WebhookSeenStore remembers which webhooks have been received, so that a
+WebhookVerifier can reject duplicates. Keys are webhook signatures, in lower
+case hex. Implementations must be safe for concurrent use.
+*/
+type WebhookSeenStore interface {
+	// Add records key, and reports false if it was already recorded.
+	Add(key string) (added bool, err error)
+	// Remove forgets key, so that a redelivery is accepted again.
+	Remove(key string) error
+}
+
+/*
+WebhookVerifier adds replay protection to `Client.Webhook`. Webhooks whose
+`time_ms` differs from the current time by more than `MaxSkew` are rejected
+with `ErrWebhookStale`, and webhooks already recorded in `SeenStore` are
+rejected with `ErrWebhookDuplicate`. Either check is disabled when left unset.
+
+To rotate credentials, list every key/secret pair that webhooks may be signed
+with in `Credentials`; the client's own pair is used when it is empty. Likewise,
+`EncryptionMasterKeys` are tried, after the client's own master key, to decrypt
+events of private-encrypted- channels.
+
+	verifier := &pusher.WebhookVerifier{
+		Client:    client,
+		MaxSkew:   5 * time.Minute,
+		SeenStore: pusher.NewWebhookLRU(10000),
+	}
+	webhook, err := verifier.Webhook(req.Header, body)
+*/
+type WebhookVerifier struct {
+	Client               *Client
+	Credentials          []WebhookCredentials
+	EncryptionMasterKeys []EncryptionMasterKey
+	MaxSkew              time.Duration
+	SeenStore            WebhookSeenStore
+	Now                  func() time.Time // the current time, time.Now by default
+}
+
+func (v *WebhookVerifier) currentTime() time.Time {
+	if v.Now == nil {
+		return time.Now()
+	}
+	return v.Now()
+}
+
+/*
+Webhook verifies a webhook in the same way as `Client.Webhook`, then applies the
+skew and duplicate checks. A webhook is only recorded as seen once it has passed
+every other check.
+*/
+func (v *WebhookVerifier) Webhook(header http.Header, body []byte) (*Webhook, error) {
+	webhook, _, err := v.VerifyWebhook(header, body)
+	return webhook, err
+}
+
+/*
+VerifyWebhook is the same as `Webhook`, except it also returns the credentials
+whose signature matched. The master key which decrypted each event is reported
+in `WebhookEvent.EncryptionMasterKeyID`.
+
+	webhook, credentials, err := verifier.VerifyWebhook(req.Header, body)
+	if err == nil && credentials.Key != newKey {
+		log.Println("webhook signed with old credentials")
+	}
+*/
+func (v *WebhookVerifier) VerifyWebhook(header http.Header, body []byte) (*Webhook, WebhookCredentials, error) {
+	credentials := v.Credentials
+	if len(credentials) == 0 {
+		credentials = []WebhookCredentials{{Key: v.Client.Key, Secret: v.Client.Secret}}
+	}
+	webhook, matched, err := v.Client.verifyWebhook(header, body, credentials, v.EncryptionMasterKeys)
+	if err != nil {
+		return nil, matched, err
+	}
+
+	if v.MaxSkew > 0 {
+		sent := time.Unix(0, int64(webhook.TimeMs)*int64(time.Millisecond))
+		skew := v.currentTime().Sub(sent)
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > v.MaxSkew {
+			return nil, matched, ErrWebhookStale
+		}
+	}
+
+	if v.SeenStore != nil {
+		added, err := v.SeenStore.Add(webhookKey(header))
+		if err != nil {
+			return nil, matched, &WebhookSeenStoreError{Err: err}
+		}
+		if !added {
+			return nil, matched, ErrWebhookDuplicate
+		}
+	}
+	return webhook, matched, nil
+}
+
+/*
+Forget removes a webhook from the seen store, so that Pusher's redelivery is
+accepted. Call it when a verified webhook could not be processed.
+*/
+func (v *WebhookVerifier) Forget(header http.Header) error {
+	if v.SeenStore == nil {
+		return nil
+	}
+	return v.SeenStore.Remove(webhookKey(header))
+}
+
+// webhookKey returns the signature of a webhook in lower case, since it is
+// accepted in any case and must not let a resent webhook pass as a new one.
+func webhookKey(header http.Header) string {
+	return strings.ToLower(header.Get("X-Pusher-Signature"))
+}
+
+/*
+WebhookLRU is an in-memory WebhookSeenStore which remembers up to a fixed number
+of the most recently received webhooks.
+*/
+type WebhookLRU struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+// defaultWebhookLRUCapacity is the capacity of a WebhookLRU created with a
+// capacity below 1.
+const defaultWebhookLRUCapacity = 10000
+
+/*
+NewWebhookLRU creates a WebhookLRU remembering up to capacity webhooks. A
+capacity below 1, which would remember nothing and so disable the duplicate
+check, is replaced with 10000.
+*/
+func NewWebhookLRU(capacity int) *WebhookLRU {
+	if capacity < 1 {
+		capacity = defaultWebhookLRUCapacity
+	}
+	return &WebhookLRU{
+		capacity: capacity,
+		order:    list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// Add implements WebhookSeenStore.
+func (l *WebhookLRU) Add(key string) (bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if element, ok := l.elements[key]; ok {
+		l.order.MoveToFront(element)
+		return false, nil
+	}
+	l.elements[key] = l.order.PushFront(key)
+	for l.order.Len() > l.capacity {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.elements, oldest.Value.(string))
+	}
+	return true, nil
+}
+
+// Remove implements WebhookSeenStore.
+func (l *WebhookLRU) Remove(key string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if element, ok := l.elements[key]; ok {
+		l.order.Remove(element)
+		delete(l.elements, key)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package pusher
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/stretchr/testify.v1/assert"
+)
+
+func signedWebhookHeader(client Client, body string) http.Header {
+	header := make(http.Header)
+	header.Set("X-Pusher-Key", client.Key)
+	header.Set("X-Pusher-Signature", hmacSignature(body, client.Secret))
+	return header
+}
+
+func setUpWebhookVerifier(client *Client, now time.Time) *WebhookVerifier {
+	return &WebhookVerifier{
+		Client:    client,
+		MaxSkew:   time.Minute,
+		SeenStore: NewWebhookLRU(10),
+		Now:       func() time.Time { return now },
+	}
+}
+
+func TestWebhookVerifierAcceptsFreshWebhook(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	body := `{"time_ms":1427233518933,"events":[]}`
+
+	webhook, err := verifier.Webhook(signedWebhookHeader(client, body), []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, 1427233518933, webhook.TimeMs)
+}
+
+func TestWebhookVerifierRejectsStaleWebhook(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0).Add(2*time.Minute))
+	body := `{"time_ms":1427233518933,"events":[]}`
+
+	webhook, err := verifier.Webhook(signedWebhookHeader(client, body), []byte(body))
+	assert.Nil(t, webhook)
+	assert.Equal(t, ErrWebhookStale, err)
+}
+
+func TestWebhookVerifierRejectsWebhookFromTheFuture(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0).Add(-2*time.Minute))
+	body := `{"time_ms":1427233518933,"events":[]}`
+
+	_, err := verifier.Webhook(signedWebhookHeader(client, body), []byte(body))
+	assert.Equal(t, ErrWebhookStale, err)
+}
+
+func TestWebhookVerifierRejectsDuplicate(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	body := `{"time_ms":1427233518933,"events":[]}`
+	header := signedWebhookHeader(client, body)
+
+	_, err := verifier.Webhook(header, []byte(body))
+	assert.NoError(t, err)
+	_, err = verifier.Webhook(header, []byte(body))
+	assert.Equal(t, ErrWebhookDuplicate, err)
+
+	assert.NoError(t, verifier.Forget(header))
+	_, err = verifier.Webhook(header, []byte(body))
+	assert.NoError(t, err)
+}
+
+func TestWebhookVerifierRejectsDuplicateWithRecasedSignature(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	body := `{"time_ms":1427233518933,"events":[]}`
+	header := signedWebhookHeader(client, body)
+
+	_, err := verifier.Webhook(header, []byte(body))
+	assert.NoError(t, err)
+
+	header.Set("X-Pusher-Signature", strings.ToUpper(header.Get("X-Pusher-Signature")))
+	_, err = verifier.Webhook(header, []byte(body))
+	assert.Equal(t, ErrWebhookDuplicate, err)
+}
+
+func TestWebhookVerifierDoesNotRecordInvalidWebhooks(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	body := `{"time_ms":1427233518933,"events":[]}`
+	header := signedWebhookHeader(client, body)
+
+	_, err := verifier.Webhook(header, []byte(`{"time_ms":1,"events":[]}`))
+	assert.Error(t, err)
+	_, err = verifier.Webhook(header, []byte(body))
+	assert.NoError(t, err)
+}
+
+func TestWebhookLRUEvictsOldest(t *testing.T) {
+	lru := NewWebhookLRU(2)
+	for i := 0; i < 3; i++ {
+		added, err := lru.Add(fmt.Sprint(i))
+		assert.True(t, added)
+		assert.NoError(t, err)
+	}
+
+	added, _ := lru.Add("0")
+	assert.True(t, added)
+	added, _ = lru.Add("2")
+	assert.False(t, added)
+}
+
+func TestWebhookLRUWithoutCapacityRemembers(t *testing.T) {
+	for _, capacity := range []int{0, -1} {
+		lru := NewWebhookLRU(capacity)
+		added, _ := lru.Add("a")
+		assert.True(t, added)
+		added, _ = lru.Add("a")
+		assert.False(t, added)
+	}
+}
+
+func TestWebhookHandlerAcknowledgesDuplicates(t *testing.T) {
+	client := setUpClient()
+	calls := 0
+	handler := &WebhookHandler{Verifier: setUpWebhookVerifier(&client, time.Unix(1427233518, 0))}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		calls++
+		return nil
+	}
+	body := `{"time_ms":1427233518933,"events":[{"name":"channel_occupied","channel":"a"}]}`
+
+	for i := 0; i < 2; i++ {
+		res := httptest.NewRecorder()
+		handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+		assert.Equal(t, http.StatusOK, res.Code)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestWebhookHandlerForgetsFailedWebhooks(t *testing.T) {
+	client := setUpClient()
+	fail := true
+	handler := &WebhookHandler{Verifier: setUpWebhookVerifier(&client, time.Unix(1427233518, 0))}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		if fail {
+			fail = false
+			return fmt.Errorf("try again")
+		}
+		return nil
+	}
+	body := `{"time_ms":1427233518933,"events":[{"name":"channel_occupied","channel":"a"}]}`
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+
+	res = httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.False(t, fail)
+}
+
+func TestWebhookVerifierAcceptsRotatedCredentials(t *testing.T) {
+	client := Client{AppID: "id", Key: "new-key", Secret: "new-secret"}
+	verifier := &WebhookVerifier{
+		Client: &client,
+		Credentials: []WebhookCredentials{
+			{Key: "new-key", Secret: "new-secret"},
+			{Key: "old-key", Secret: "old-secret"},
+		},
+	}
+	body := `{"time_ms":1,"events":[]}`
+
+	_, matched, err := verifier.VerifyWebhook(signedWebhookHeader(Client{Key: "old-key", Secret: "old-secret"}, body), []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, "old-key", matched.Key)
+
+	_, matched, err = verifier.VerifyWebhook(signedWebhookHeader(client, body), []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, "new-key", matched.Key)
+
+	_, _, err = verifier.VerifyWebhook(signedWebhookHeader(Client{Key: "old-key", Secret: "new-secret"}, body), []byte(body))
+	assert.EqualError(t, err, "Invalid webhook")
+}
+
+func TestWebhookVerifierDecryptsWithRotatedMasterKey(t *testing.T) {
+	oldKey := []byte("This is a string that is 32 char")
+	client := Client{AppID: "id", Key: "key", Secret: "secret", EncryptionMasterKeyBase64: "ZUhQVldIZzduRkdZVkJzS2pPRkRYV1JyaWJJUjJiMGI="}
+	verifier := &WebhookVerifier{
+		Client:               &client,
+		EncryptionMasterKeys: []EncryptionMasterKey{{ID: "2020", KeyBase64: base64.StdEncoding.EncodeToString(oldKey)}},
+	}
+	data, _ := json.Marshal(encrypt("private-encrypted-a", []byte("Hello!"), oldKey))
+	body := fmt.Sprintf(`{"time_ms":1,"events":[{"name":"client_event","channel":"private-encrypted-a","event":"client-a","data":%s},{"name":"channel_occupied","channel":"a"}]}`, data)
+
+	webhook, err := verifier.Webhook(signedWebhookHeader(client, body), []byte(body))
+	assert.NoError(t, err)
+	assert.Equal(t, "Hello!", webhook.Events[0].Data)
+	assert.Equal(t, "2020", webhook.Events[0].EncryptionMasterKeyID)
+	assert.Equal(t, "", webhook.Events[1].EncryptionMasterKeyID)
+}
+
+func TestWebhookVerifierRejectsInvalidMasterKey(t *testing.T) {
+	client := setUpClient()
+	verifier := &WebhookVerifier{
+		Client:               &client,
+		EncryptionMasterKeys: []EncryptionMasterKey{{ID: "short", KeyBase64: "c2hvcnQ="}},
+	}
+	body := `{"time_ms":1,"events":[{"name":"client_event","channel":"private-encrypted-a","data":"{}"}]}`
+
+	_, err := verifier.Webhook(signedWebhookHeader(client, body), []byte(body))
+	assert.EqualError(t, err, "Encryption master key 'short' must encode 32 bytes")
+}
+
+func TestWebhookHandlerAcknowledgesStaleWebhooks(t *testing.T) {
+	client := setUpClient()
+	handler := &WebhookHandler{Verifier: setUpWebhookVerifier(&client, time.Unix(1427233518, 0).Add(time.Hour))}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		t.Fatal("No callback should be invoked")
+		return nil
+	}
+	body := `{"time_ms":1427233518933,"events":[{"name":"channel_occupied","channel":"a"}]}`
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusAccepted, res.Code)
+}
+
+type failingSeenStore struct {
+	addErr error
+}
+
+func (s failingSeenStore) Add(key string) (bool, error) { return true, s.addErr }
+func (failingSeenStore) Remove(key string) error        { return fmt.Errorf("store unavailable") }
+
+func TestWebhookHandlerReportsForgetErrors(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	verifier.SeenStore = failingSeenStore{}
+	var reported error
+	handler := &WebhookHandler{Verifier: verifier, OnError: func(err error) { reported = err }}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		return fmt.Errorf("try again")
+	}
+	body := `{"time_ms":1427233518933,"events":[{"name":"channel_occupied","channel":"a"}]}`
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.EqualError(t, reported, "store unavailable")
+}
+
+func TestWebhookHandlerRetriesWhenSeenStoreFails(t *testing.T) {
+	client := setUpClient()
+	verifier := setUpWebhookVerifier(&client, time.Unix(1427233518, 0))
+	verifier.SeenStore = failingSeenStore{addErr: fmt.Errorf("store unavailable")}
+	var reported error
+	handler := &WebhookHandler{Verifier: verifier, OnError: func(err error) { reported = err }}
+	handler.OnChannelOccupied = func(e ChannelOccupiedEvent) error {
+		t.Fatal("No callback should be invoked")
+		return nil
+	}
+	body := `{"time_ms":1427233518933,"events":[{"name":"channel_occupied","channel":"a"}]}`
+
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, newSignedWebhookRequest(client, body))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+	assert.EqualError(t, reported, "Could not check the webhook for duplicates: store unavailable")
+}